@@ -0,0 +1,424 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command pipeline runs pipelines over JSON, NDJSON or CSV documents read from
+// files or stdin, the way jq does:
+//
+//	pipeline -in ndjson -filter 'status == "error"' -groupby service -count -out table < logs.ndjson
+//
+// Operators are applied in the order of the flags, see pipeline -help.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/interactiv/pipeline"
+)
+
+const usage = `usage: pipeline [flags] [file ...]
+
+Reads the files, or stdin, and writes the result to stdout.
+Operators are applied in the order they are given.
+
+input and output:
+  -in auto|json|ndjson|csv   input format, auto detects JSON arrays and NDJSON (default auto)
+  -out json|ndjson|csv|table output format (default json)
+
+operators:
+  -filter EXPR    keeps the elements for which EXPR is true
+  -map EXPR       replaces each element by EXPR
+  -groupby EXPR   groups the elements by EXPR into {EXPR: key, items: [...]}
+  -count          counts the elements of each group, or of the collection
+  -sort EXPR      sorts the elements by EXPR
+  -desc           sorts the previous -sort in descending order
+  -limit N        keeps the first N elements
+  -unique         removes duplicates
+  -reverse        reverses the collection
+  -flatten        flattens nested collections
+  -sql QUERY      runs a SELECT query, FROM ? being the collection
+  -spec FILE      applies a JSON pipeline spec
+
+EXPR is an expression such as 'status == "error" && len(message) > 10', fields are
+resolved against each element. CSV documents must have a header row.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pipeline:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses args, reads the input from the files of args or stdin and writes the result to stdout
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	input, output := "auto", "json"
+	files := []string{}
+	steps := []func(p *pipeline.Pipeline) error{}
+	grouped, lastSort := false, -1
+	var sortBy *pipeline.Expr
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		switch name {
+		case "in", "out", "filter", "map", "groupby", "sort", "limit", "sql", "spec":
+			if i+1 >= len(args) {
+				return fmt.Errorf("flag -%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "h", "help":
+			_, err := io.WriteString(stdout, usage)
+			return err
+		case "in":
+			input = value
+		case "out":
+			output = value
+		case "filter", "map":
+			if _, err := pipeline.Compile(value); err != nil {
+				return fmt.Errorf("-%s : %s", name, err)
+			}
+			steps = append(steps, exprStep(name, value))
+		case "groupby":
+			expr, err := pipeline.Compile(value)
+			if err != nil {
+				return fmt.Errorf("-groupby : %s", err)
+			}
+			grouped = true
+			steps = append(steps, groupByStep(expr))
+		case "count":
+			steps = append(steps, countStep(grouped))
+			grouped = false
+		case "sort":
+			expr, err := pipeline.Compile(value)
+			if err != nil {
+				return fmt.Errorf("-sort : %s", err)
+			}
+			lastSort, sortBy = len(steps), expr
+			steps = append(steps, sortStep(expr, false))
+		case "desc":
+			if lastSort < 0 || lastSort != len(steps)-1 {
+				return fmt.Errorf("-desc must follow -sort")
+			}
+			steps[lastSort] = sortStep(sortBy, true)
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("-limit : invalid count %q", value)
+			}
+			steps = append(steps, queryStep(fmt.Sprintf("SELECT * FROM ? LIMIT %d", n)))
+		case "unique":
+			steps = append(steps, func(p *pipeline.Pipeline) error { p.Unique(); return nil })
+		case "reverse":
+			steps = append(steps, func(p *pipeline.Pipeline) error { p.Reverse(); return nil })
+		case "flatten":
+			steps = append(steps, func(p *pipeline.Pipeline) error { p.Flatten(); return nil })
+		case "sql":
+			if _, err := pipeline.PrepareQuery(value); err != nil {
+				return fmt.Errorf("-sql : %s", err)
+			}
+			steps = append(steps, queryStep(value))
+		case "spec":
+			data, err := os.ReadFile(value)
+			if err != nil {
+				return err
+			}
+			spec, err := pipeline.LoadSpec(data)
+			if err != nil {
+				return fmt.Errorf("-spec %s : %s", value, err)
+			}
+			steps = append(steps, func(p *pipeline.Pipeline) error {
+				_, err := spec.Apply(p)
+				return err
+			})
+		default:
+			return fmt.Errorf("unknown flag %s, see pipeline -help", arg)
+		}
+	}
+	p, err := read(input, files, stdin)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if err := step(p); err != nil {
+			return err
+		}
+	}
+	return write(p, output, stdout)
+}
+
+func exprStep(name string, source string) func(p *pipeline.Pipeline) error {
+	return func(p *pipeline.Pipeline) error {
+		if name == "filter" {
+			p.FilterExpr(source)
+		} else {
+			p.MapExpr(source)
+		}
+		return nil
+	}
+}
+
+// queryStep runs a query over the collection
+func queryStep(query string) func(p *pipeline.Pipeline) error {
+	return func(p *pipeline.Pipeline) error {
+		p.Op(func(in interface{}) (interface{}, error) {
+			var result []interface{}
+			err := pipeline.Query(query, in).Out(&result)
+			return result, err
+		})
+		return nil
+	}
+}
+
+// sortStep sorts the elements by the value of expr, the query ordering them only sees
+// the values, not the source of expr
+func sortStep(expr *pipeline.Expr, desc bool) func(p *pipeline.Pipeline) error {
+	query := "SELECT value FROM ? ORDER BY key"
+	if desc {
+		query += " DESC"
+	}
+	return func(p *pipeline.Pipeline) error {
+		p.Op(func(in interface{}) (interface{}, error) {
+			var items []interface{}
+			if err := pipeline.In(in).Out(&items); err != nil {
+				return nil, err
+			}
+			keyed := []interface{}{}
+			for _, item := range items {
+				key, err := expr.Eval(item)
+				if err != nil {
+					return nil, err
+				}
+				keyed = append(keyed, map[string]interface{}{"key": key, "value": item})
+			}
+			var rows []map[string]interface{}
+			if err := pipeline.Query(query, keyed).Out(&rows); err != nil {
+				return nil, err
+			}
+			sorted := []interface{}{}
+			for _, row := range rows {
+				sorted = append(sorted, row["value"])
+			}
+			return sorted, nil
+		})
+		return nil
+	}
+}
+
+// groupByStep groups the elements by the value of expr, groups keep the order of their first element
+func groupByStep(expr *pipeline.Expr) func(p *pipeline.Pipeline) error {
+	return func(p *pipeline.Pipeline) error {
+		p.Op(func(in interface{}) (interface{}, error) {
+			groups := []interface{}{}
+			indexes := map[string]int{}
+			var items []interface{}
+			if err := pipeline.In(in).Out(&items); err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				key, err := expr.Eval(item)
+				if err != nil {
+					return nil, err
+				}
+				id := fmt.Sprintf("%#v", key)
+				if _, ok := indexes[id]; !ok {
+					indexes[id] = len(groups)
+					groups = append(groups, map[string]interface{}{expr.String(): key, "items": []interface{}{}})
+				}
+				group := groups[indexes[id]].(map[string]interface{})
+				group["items"] = append(group["items"].([]interface{}), item)
+			}
+			return groups, nil
+		})
+		return nil
+	}
+}
+
+// countStep replaces the items of each group by their count if grouped,
+// counts the elements of the collection otherwise
+func countStep(grouped bool) func(p *pipeline.Pipeline) error {
+	return func(p *pipeline.Pipeline) error {
+		if !grouped {
+			p.Op(func(in interface{}) (interface{}, error) {
+				var items []interface{}
+				err := pipeline.In(in).Out(&items)
+				return []interface{}{len(items)}, err
+			})
+			return nil
+		}
+		p.Map(func(element interface{}, index int) interface{} {
+			group := map[string]interface{}{}
+			for key, value := range element.(map[string]interface{}) {
+				group[key] = value
+			}
+			group["count"] = len(group["items"].([]interface{}))
+			delete(group, "items")
+			return group
+		})
+		return nil
+	}
+}
+
+// read returns a pipeline reading the files, or stdin if there are none
+func read(format string, files []string, stdin io.Reader) (*pipeline.Pipeline, error) {
+	if len(files) == 0 {
+		return decode(format, stdin)
+	}
+	all := []interface{}{}
+	for _, name := range files {
+		var reader io.Reader = stdin
+		if name != "-" {
+			file, err := os.Open(name)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			reader = file
+		}
+		p, err := decode(format, reader)
+		if err != nil {
+			return nil, err
+		}
+		var elements []interface{}
+		if err := p.Out(&elements); err != nil {
+			return nil, fmt.Errorf("%s : %s", name, err)
+		}
+		all = append(all, elements...)
+	}
+	return pipeline.In(all), nil
+}
+
+func decode(format string, reader io.Reader) (*pipeline.Pipeline, error) {
+	if format == "auto" {
+		buffered := bufio.NewReader(reader)
+		format = "ndjson"
+		for {
+			r, _, err := buffered.ReadRune()
+			if err != nil {
+				break
+			}
+			if r == '[' {
+				format = "json"
+			}
+			if !strings.ContainsRune(" \t\r\n", r) {
+				buffered.UnreadRune()
+				break
+			}
+		}
+		reader = buffered
+	}
+	switch format {
+	case "json":
+		return pipeline.FromJSONArray(reader, nil), nil
+	case "ndjson":
+		return pipeline.FromNDJSON(reader, nil), nil
+	case "csv":
+		return pipeline.FromCSV(reader, pipeline.CSVOptions{Header: true, Maps: true}).Map(func(element interface{}, index int) interface{} {
+			record := map[string]interface{}{}
+			for key, text := range element.(map[string]string) {
+				record[key] = parseCell(text)
+			}
+			return record
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+// decimal matches plain decimal numbers, without leading zeros
+var decimal = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// parseCell converts the numbers of CSV documents so that they can be compared.
+// Other cells, such as "007", "1e3" or "NaN", are kept as strings.
+func parseCell(text string) interface{} {
+	if !decimal.MatchString(text) {
+		return text
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	return text
+}
+
+func write(p *pipeline.Pipeline, format string, stdout io.Writer) error {
+	switch format {
+	case "json":
+		return p.ToJSON(stdout)
+	case "ndjson":
+		return p.ToNDJSON(stdout)
+	case "csv":
+		return p.ToCSV(stdout)
+	case "table":
+		return writeTable(p, stdout)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// writeTable writes the elements as aligned columns, the columns are the sorted keys of
+// the first element if it is an object
+func writeTable(p *pipeline.Pipeline, stdout io.Writer) error {
+	var rows []interface{}
+	if err := p.Out(&rows); err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	columns := []string{}
+	if len(rows) > 0 {
+		if first, ok := rows[0].(map[string]interface{}); ok {
+			for column := range first {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			fmt.Fprintln(writer, strings.Join(columns, "\t"))
+		}
+	}
+	for _, row := range rows {
+		record, ok := row.(map[string]interface{})
+		if len(columns) == 0 || !ok {
+			fmt.Fprintln(writer, cell(row))
+			continue
+		}
+		cells := []string{}
+		for _, column := range columns {
+			cells = append(cells, cell(record[column]))
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	return writer.Flush()
+}
+
+func cell(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,104 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+)
+
+const logs = `{"service":"api","status":"error","latency":120}
+{"service":"db","status":"ok","latency":15}
+{"service":"api","status":"error","latency":340}
+{"service":"web","status":"error","latency":80}
+{"service":"api","status":"ok","latency":30}
+`
+
+func TestRun(t *testing.T) {
+	e := expect.New(t)
+	for args, expected := range map[string]string{
+		`-in ndjson -filter status=="error" -groupby service -count -out table`:                  "count  service\n2      api\n1      web\n",
+		`-filter latency>50 -sort latency -desc -limit 2 -map service -out ndjson`:               "\"api\"\n\"api\"\n",
+		`-sql SELECT&service,&MAX(latency)&AS&max&FROM&?&GROUP&BY&service&ORDER&BY&max -out csv`: "max,service\n15,db\n80,web\n340,api\n",
+		`-count`:                       "[5]\n",
+		`-map status -unique -reverse`: `["ok","error"]` + "\n",
+	} {
+		stdout := &bytes.Buffer{}
+		err := run(splitArgs(args), strings.NewReader(logs), stdout)
+		e.Expect(err).ToBeNil()
+		e.Expect(stdout.String()).ToEqual(expected)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	e := expect.New(t)
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "prices.csv")
+	specFile := filepath.Join(dir, "spec.json")
+	e.Expect(os.WriteFile(csvFile, []byte("name,price\ntea,12\ncoffee,25.5\nsugar,5\n"), 0644)).ToBeNil()
+	e.Expect(os.WriteFile(specFile, []byte(`[{"op":"filter","expr":"price > 10"},{"op":"map","expr":"name"}]`), 0644)).ToBeNil()
+	stdout := &bytes.Buffer{}
+	err := run([]string{"-in", "csv", "-spec", specFile, csvFile, csvFile}, nil, stdout)
+	e.Expect(err).ToBeNil()
+	e.Expect(stdout.String()).ToEqual(`["tea","coffee","tea","coffee"]` + "\n")
+
+	stdout.Reset()
+	err = run([]string{"-out", "table"}, strings.NewReader(`[{"a":1,"b":"x"},{"a":22}]`), stdout)
+	e.Expect(err).ToBeNil()
+	e.Expect(stdout.String()).ToEqual("a   b\n1   x\n22  \n")
+
+	// only plain decimal numbers are converted
+	stdout.Reset()
+	err = run([]string{"-in", "csv"}, strings.NewReader("id,a,b,c,d\n007,nan,1e3,0x1p3,-2.5\n"), stdout)
+	e.Expect(err).ToBeNil()
+	e.Expect(stdout.String()).ToEqual(`[{"a":"nan","b":"1e3","c":"0x1p3","d":-2.5,"id":"007"}]` + "\n")
+}
+
+func TestRunErrors(t *testing.T) {
+	e := expect.New(t)
+	for args, message := range map[string]string{
+		"-filter":                "flag -filter needs a value",
+		"-filter status==":       "-filter : Parse error at column 9 : unexpected end of expression",
+		"-desc":                  "-desc must follow -sort",
+		"-sort latency&LIMIT&1":  `-sort : Parse error at column 9 : unexpected "LIMIT"`,
+		"-limit -1":              `-limit : invalid count "-1"`,
+		"-explode":               "unknown flag -explode, see pipeline -help",
+		"-out xml":               `unknown output format "xml"`,
+		"-in yaml":               `unknown input format "yaml"`,
+		"-sql SELECT&*&FROM&?&,": `-sql : Parse error at column 17 : unexpected ","`,
+	} {
+		err := run(splitArgs(args), strings.NewReader(logs), &bytes.Buffer{})
+		e.Expect(err).Not().ToBeNil()
+		e.Expect(err.Error()).ToEqual(message)
+	}
+	err := run([]string{"-filter", "latency > 'x'"}, strings.NewReader(logs), &bytes.Buffer{})
+	e.Expect(err).Not().ToBeNil()
+}
+
+// splitArgs splits args on spaces, & stands for a space inside an argument
+func splitArgs(args string) []string {
+	result := []string{}
+	for _, arg := range strings.Fields(args) {
+		result = append(result, strings.Replace(arg, "&", " ", -1))
+	}
+	return result
+}
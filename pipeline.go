@@ -17,10 +17,15 @@
 package pipeline
 
 import (
+	"encoding"
 	"fmt"
-	"log"
+	"io"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // Array is a place holder for interface{}
@@ -35,331 +40,374 @@ const VERSION = "0.1"
 
 // Pipeline allow sequential operations on slices, arrays or strings
 type Pipeline struct {
-	in       Array
-	commands []func() (interface{}, error)
-	current  interface{}
+	in          Array
+	commands    []command
+	current     interface{}
+	observers   []Observer
+	optimized   bool
+	checkSorted bool
+	sideInputs  []sideInput
+	context     *Context
+	onError     ErrorMode
+	deadLetters []DeadLetter
+}
+
+// command is a step of a pipeline, name and params describe it for Explain
+type command struct {
+	name     string
+	params   []interface{}
+	spec     *StepSpec
+	registry *Registry
+	run      func() (interface{}, error)
+}
+
+// push adds a step to the pipeline
+func (pipeline *Pipeline) push(name string, run func() (interface{}, error), params ...interface{}) *Pipeline {
+	pipeline.commands = append(pipeline.commands, command{name: name, params: params, run: run})
+	return pipeline
 }
 
 // Map send each element of a iterable through a function and return an array of results
 func (pipeline *Pipeline) Map(callback func(interface{}, int) interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Map", func() (interface{}, error) {
 		return Map(pipeline.in, callback)
-	})
-	return pipeline
+	}, callback)
+}
+
+// FlatMap send each element of a iterable through a function and flattens the results
+func (pipeline *Pipeline) FlatMap(callback func(interface{}, int) interface{}) *Pipeline {
+	return pipeline.push("FlatMap", func() (interface{}, error) {
+		return FlatMap(pipeline.in, callback)
+	}, callback)
 }
 
 // Reduce folds the array into a single value
 func (pipeline *Pipeline) Reduce(callback func(result interface{}, element interface{}, index int) interface{}, initialOrNil interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Reduce", func() (interface{}, error) {
 		return Reduce(pipeline.in, callback, initialOrNil)
-	})
-	return pipeline
+	}, callback, initialOrNil)
 }
 
 // ReduceRight folds the array from end into a single value
 func (pipeline *Pipeline) ReduceRight(callback func(result interface{}, element interface{}, index int) interface{}, initialOrNil interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("ReduceRight", func() (interface{}, error) {
 		return ReduceRight(pipeline.in, callback, initialOrNil)
-	})
-	return pipeline
+	}, callback, initialOrNil)
 }
 
-// Sort sorts an array given a compare function
+// Sort sorts an array given a compare function, equal elements keep their order
 func (pipeline *Pipeline) Sort(compareFunc func(a, b interface{}) bool) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Sort", func() (interface{}, error) {
 		return Sort(pipeline.in, compareFunc)
-	})
-	return pipeline
+	}, compareFunc)
 }
 
 // Filter Iterates over elements of collection, returning a collection of all elements the predicate returns truthy for
 func (pipeline *Pipeline) Filter(predicate func(element interface{}, index int) bool) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Filter", func() (interface{}, error) {
 		return Filter(pipeline.in, predicate)
-	})
-	return pipeline
+	}, predicate)
 }
 
 // Flattens a nested array.
 func (pipeline *Pipeline) Flatten() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Flatten", func() (interface{}, error) {
 		return Flatten(pipeline.in)
 	})
-	return pipeline
 }
 
 // Compact remove nil values from array
 func (pipeline *Pipeline) Compact() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Compact", func() (interface{}, error) {
 		return Compact(pipeline.in)
 	})
-	return pipeline
 }
 
 // Intersection creates a collection of unique values that are included in all
 // of the provided collections.
 func (pipeline *Pipeline) Intersection(arrays ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Intersection", func() (interface{}, error) {
 		return Intersection(append(append([]interface{}{}, pipeline.in), arrays...)...)
-	})
-	return pipeline
+	}, arrays)
 }
 
 // IndexOf returns the index at which the first occurrence of element is found in array
 // or -1 if the element is not found
 func (pipeline *Pipeline) IndexOf(value interface{}, fromIndex int) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("IndexOf", func() (interface{}, error) {
 		return IndexOf(pipeline.in, value, fromIndex)
-	})
-	return pipeline
+	}, value, fromIndex)
 }
 
 // LastIndexOf method returns the last index at which a given element
 // can be found in the array, or -1 if it is not present. The array is searched backwards, starting at fromIndex.
 func (pipeline *Pipeline) LastIndexOf(value interface{}, fromIndex int) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("LastIndexOf", func() (interface{}, error) {
 		return LastIndexOf(pipeline.in, value, fromIndex)
-	})
-	return pipeline
+	}, value, fromIndex)
 }
 
 // Concat adds arrays to the end of the array and returns an new array
 func (pipeline *Pipeline) Concat(arrays ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Concat", func() (interface{}, error) {
 		return Concat(pipeline.in, arrays...)
-	})
-	return pipeline
+	}, arrays)
 }
 
 // Zip creates an array of grouped elements,
 // the first of which contains the first elements of the given arrays,
 // the second of which contains the second elements of the given arrays, and so on.
 func (pipeline *Pipeline) Zip() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Zip", func() (interface{}, error) {
 		return Zip(pipeline.in)
 	})
-	return pipeline
 }
 
 // Chunk Creates an array of elements split into groups the length of size. If collection can’t be split evenly, the final chunk will be the remaining elements.
 func (pipeline *Pipeline) Chunk(length int) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Chunk", func() (interface{}, error) {
 		return Chunk(pipeline.in, length)
-	})
-	return pipeline
+	}, length)
 }
 
 // Reverse reverse the order of the elements of the array and returns a new one
 func (pipeline *Pipeline) Reverse() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Reverse", func() (interface{}, error) {
 		return Reverse(pipeline.in)
 	})
-	return pipeline
 }
 
 // Some returns true if the callback predicate is satisfied
 func (pipeline *Pipeline) Some(predicate func(element interface{}, index int) bool) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Some", func() (interface{}, error) {
 		return Some(pipeline.in, predicate)
-	})
-	return pipeline
+	}, predicate)
 }
 
 // Push adds an element at the  end of the array
 func (pipeline *Pipeline) Push(values ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Push", func() (interface{}, error) {
 		return Push(pipeline.in, values...)
-	})
-	return pipeline
+	}, values)
 }
 
 // Unshift add an element at the beginning of a collection
 func (pipeline *Pipeline) Unshift(values ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Unshift", func() (interface{}, error) {
 		return Unshift(pipeline.in, values...)
-	})
-	return pipeline
+	}, values)
 }
 
 // Every returns true if the callback predicate is true for every element of the array
 func (pipeline *Pipeline) Every(predicate func(element interface{}, index int) bool) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Every", func() (interface{}, error) {
 		return Every(pipeline.in, predicate)
-	})
-	return pipeline
+	}, predicate)
 }
 
 // First returns the first element
 func (pipeline *Pipeline) First() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("First", func() (interface{}, error) {
 		return First(pipeline.in)
 	})
-	return pipeline
 }
 
 // GroupBy Creates a map composed of keys generated
 // from the results of running each element of collection through iteratee
 func (pipeline *Pipeline) GroupBy(iteratee func(element interface{}, index int) interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("GroupBy", func() (interface{}, error) {
 		return GroupBy(pipeline.in, iteratee)
-	})
-	return pipeline
+	}, iteratee)
 }
 
-// Op insert a custom operation in the pipeline
-func (pipeline *Pipeline) Op(callback func(in interface{}) (interface{}, error)) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
-		return callback(pipeline.in)
-	})
-	return pipeline
+// Op insert a custom operation in the pipeline, policies such as Retry, Timeout
+// or Fallback change how callback is called
+func (pipeline *Pipeline) Op(callback func(in interface{}) (interface{}, error), policies ...Policy) *Pipeline {
+	return pipeline.push("Op", func() (interface{}, error) {
+		if len(policies) == 0 {
+			return callback(pipeline.in)
+		}
+		result, attempts, err := newPolicies(policies).call(pipeline.in, callback)
+		if err != nil {
+			return nil, attemptsFailure{attempts, err}
+		}
+		return result, nil
+	}, append([]interface{}{callback}, policyParams(policies)...)...)
 }
 
 // Last returns the last element
 func (pipeline *Pipeline) Last() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Last", func() (interface{}, error) {
 		return Last(pipeline.in)
 	})
-	return pipeline
 }
 
 // Head returns the head until end
 func (pipeline *Pipeline) Head(end int) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Head", func() (interface{}, error) {
 		return Head(pipeline.in, end)
-	})
-	return pipeline
+	}, end)
 }
 
 // Tail returns the tail starting from start
 func (pipeline *Pipeline) Tail(start int) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Tail", func() (interface{}, error) {
 		return Tail(pipeline.in, start)
-	})
-	return pipeline
+	}, start)
 }
 
 // ToMap takes a collection or a map and a callback, and returns a map[interface{}]interface{}
 func (pipeline *Pipeline) ToMap(callback func(value interface{}, key interface{}) (resultValue interface{}, resultKey interface{})) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("ToMap", func() (interface{}, error) {
 		return ToMap(pipeline.in, callback)
-	})
-	return pipeline
+	}, callback)
 }
 
 // Slice returns a slice of an array
 func (pipeline *Pipeline) Slice(start int, end int) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Slice", func() (interface{}, error) {
 		return Slice(pipeline.in, start, end)
-	})
-	return pipeline
+	}, start, end)
 }
 
 // Unique returns all the unique elements in a collection
 func (pipeline *Pipeline) Unique() *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Unique", func() (interface{}, error) {
 		return Unique(pipeline.in)
 	})
-	return pipeline
 }
 
 // Splice  deletes 'deleteCount' elements of an array from 'start' index
 // and optionally inserts 'items'
 func (pipeline *Pipeline) Splice(start int, deleteCount int, items ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Splice", func() (interface{}, error) {
 		return Splice(pipeline.in, start, deleteCount, items...)
-	})
-	return pipeline
+	}, start, deleteCount, items)
 }
 
 // Union returns an array filled by all unique values of the arrays
 func (pipeline *Pipeline) Union(arrays ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Union", func() (interface{}, error) {
 		return Union(append(append([]interface{}{}, pipeline.in), arrays...)...)
-	})
-	return pipeline
+	}, arrays)
 }
 
 // Difference returns a collection of the differences between 2 collections
 func (pipeline *Pipeline) Difference(array interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Difference", func() (interface{}, error) {
 		return Difference(pipeline.in, array)
-	})
-	return pipeline
+	}, array)
 }
 
 // Without returns a collection without the values
 func (pipeline *Pipeline) Without(values ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Without", func() (interface{}, error) {
 		return Without(pipeline.in, values...)
-	})
-	return pipeline
+	}, values)
 }
 
 // Xor creates an array of unique values that is the symmetric difference of the provided arrays.
 func (pipeline *Pipeline) Xor(arrays ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Xor", func() (interface{}, error) {
 		return Xor(append(append([]interface{}{}, pipeline.in), arrays...)...)
-	})
-	return pipeline
+	}, arrays)
 }
 
 // Out sets the output for the pipeline or return an error if an operation has failed
-// output must be a pointer.
+// output must be a pointer. The pipeline can run again, except for channels and Iterators
+// which are read once.
 func (pipeline *Pipeline) Out(output interface{}) error {
-	// output must be a pointer !
-	if !isPointer(output) {
-		return NotAPointerError{output}
-	}
-	// execute pipeline
-	for i, command := range pipeline.commands {
-		current, err := command()
-		if err != nil {
-			return StepError{i + 1, err}
+	return pipeline.OutWith(output, OutOptions{})
+}
+
+// run executes the commands of the pipeline and returns its result. The source of the
+// pipeline is restored afterwards so that the pipeline can run again.
+func (pipeline *Pipeline) run() (result interface{}, err error) {
+	source := pipeline.in
+	var id uint64
+	if len(pipeline.observers) > 0 {
+		id = atomic.AddUint64(&runs, 1)
+	}
+	started := false
+	defer func() {
+		if started {
+			for _, observer := range pipeline.observers {
+				if runObserver, ok := observer.(RunObserver); ok {
+					runObserver.OnRunEnd(id, err)
+				}
+			}
 		}
-		pipeline.in = current
-
-	}
-	// first try
-	if canAssignTo(pipeline.in, output) {
-		valueOf(output).Elem().Set(valueOf(pipeline.in))
-		return nil
+		// the steps are done with a lazy source, even if they stopped reading it early
+		if len(pipeline.commands) > 0 {
+			closeSource(source)
+		}
+		pipeline.in, pipeline.context = source, nil
+	}()
+	pipeline.deadLetters = nil
+	context, err := pipeline.resolveSideInputs()
+	if err != nil {
+		return nil, err
 	}
-	// if in and out are maps let's try to match them
-	if isMap(pipeline.in) && isMap(output) {
-		maP := makeMapFrom(output)
-		candidate := valueOf(pipeline.in)
-		for _, key := range candidate.MapKeys() {
-			if (candidate.MapIndex(key).Kind() == reflect.Ptr || candidate.MapIndex(key).Kind() == reflect.Interface) && canAssignTo(candidate.MapIndex(key).Elem(), maP) {
-				maP.SetMapIndex(key.Elem(), candidate.MapIndex(key).Elem())
-			} else if isSlice(candidate.MapIndex(key).Interface()) {
-				val, err := convertSliceOfInterfaceToTypedSlice(candidate.MapIndex(key).Interface(), maP.Type().Elem())
-				if err != nil {
-					return err
-				}
-				maP.SetMapIndex(key.Elem(), val)
+	pipeline.context = context
+	for _, step := range pipeline.plan() {
+		command := step.command
+		source := pipeline.in
+		event := StepEvent{Run: id, Step: step.last, Steps: len(pipeline.commands), Name: command.name, InLen: -1, OutLen: -1}
+		if len(pipeline.observers) > 0 {
+			started = true
+			event.InLen = length(source)
+			for _, observer := range pipeline.observers {
+				observer.OnStepStart(event)
 			}
 		}
-		pipeline.in = maP.Interface()
-	}
-	// If in and out are slices , let's try to match them
-	if isSlice(pipeline.in) && isSlice(output) {
-		arr := reflect.MakeSlice(reflect.TypeOf(output).Elem(), 0, 0)
-		it := NewIterable(pipeline.in)
-		for i := 0; i < it.Length(); i++ {
-			val := it.At(i)
-			// if arr is not of type []interface{} and []val cannot be assigned to arr
-			if reflect.TypeOf(arr.Interface()) != reflect.TypeOf([]interface{}{}) && !reflect.SliceOf(reflect.TypeOf(val)).AssignableTo(reflect.TypeOf(arr.Interface())) {
-				return CannotAppendError{arr.Interface(), val}
+		start := time.Now()
+		current, err := command.run()
+		number, attempts := step.first, 1
+		if failure, ok := err.(stepFailure); ok {
+			number, err = failure.step, failure.err
+		}
+		if failure, ok := err.(attemptsFailure); ok {
+			attempts, err = failure.attempts, failure.err
+		}
+		if err == nil {
+			err = iteratorError(source)
+		}
+		if len(pipeline.observers) > 0 {
+			event.Duration, event.Err = time.Since(start), err
+			if err == nil {
+				event.OutLen = length(current)
+			} else {
+				event.Step = number
 			}
-			arr = reflect.Append(arr, valueOf(val))
+			for _, observer := range pipeline.observers {
+				observer.OnStepEnd(event)
+			}
+		}
+		if err != nil {
+			return nil, StepError{number, err, attempts}
 		}
-		pipeline.in = arr.Interface()
+		pipeline.in = current
 	}
-	// if in can be assigned to out , do it
-	if canAssignTo(pipeline.in, output) {
-		valueOf(output).Elem().Set(valueOf(pipeline.in))
-	} else {
-		return CannotAssignError{pipeline.in, output}
+	return pipeline.in, nil
+}
+
+// sink executes the pipeline and passes each element of the result to write.
+// Lazy sources are read element by element.
+func (pipeline *Pipeline) sink(write func(element interface{}, index int) error) error {
+	result, err := pipeline.run()
+	if err != nil {
+		return err
+	}
+	var Error error
+	err = each(result, func(element interface{}, index int) bool {
+		Error = write(element, index)
+		return Error == nil
+	})
+	if Error == nil {
+		Error = err
+	}
+	if Error != nil {
+		return StepError{len(pipeline.commands) + 1, Error, 1}
 	}
 	return nil
 }
@@ -376,15 +424,14 @@ func (pipeline *Pipeline) MustOut() interface{} {
 
 // Equals returns true if all arrays are of equal length and Equal content
 func (pipeline *Pipeline) Equals(arrays ...interface{}) *Pipeline {
-	pipeline.commands = append(pipeline.commands, func() (interface{}, error) {
+	return pipeline.push("Equals", func() (interface{}, error) {
 		return Equals(append(append([]interface{}{}, pipeline.in), arrays...)...)
-	})
-	return pipeline
+	}, arrays)
 }
 
 // In Returns a new Pipeline
 func In(sliceOrStringOrMap Array) *Pipeline {
-	return &Pipeline{in: sliceOrStringOrMap, commands: []func() (interface{}, error){}}
+	return &Pipeline{in: sliceOrStringOrMap, commands: []command{}}
 }
 
 // Must returns value or panics if err is not nil
@@ -411,9 +458,11 @@ func IsIterable(value interface{}) bool {
 	switch arrayValue.Kind() {
 	case reflect.Array, reflect.Slice, reflect.String, reflect.Map:
 		return true
+	case reflect.Chan:
+		return arrayValue.Type().ChanDir()&reflect.RecvDir != 0
 	default:
 		switch value.(type) {
-		case IterableInterface:
+		case IterableInterface, Iterator:
 			return true
 		default:
 			return false
@@ -429,7 +478,9 @@ func Map(value interface{}, callback func(interface{}, int) interface{}) (interf
 	}
 	iterable := NewIterable(value)
 	var result []interface{}
-
+	if iterable.Length() > 0 {
+		result = make([]interface{}, 0, iterable.Length())
+	}
 	for i := 0; i < iterable.Length(); i++ {
 		val := iterable.At(i)
 		result = append(result, callback(val, i))
@@ -437,6 +488,38 @@ func Map(value interface{}, callback func(interface{}, int) interface{}) (interf
 	return result, nil
 }
 
+// FlatMap maps each element through callback and flattens the results by one level.
+// callback may return a collection, a lazy source or a *Pipeline, which is executed.
+func FlatMap(value interface{}, callback func(interface{}, int) interface{}) (interface{}, error) {
+	if !IsIterable(value) {
+		return nil, NotIterableError{value}
+	}
+	result := []interface{}{}
+	var Error error
+	err := each(value, func(element interface{}, index int) bool {
+		mapped := callback(element, index)
+		if pipeline, ok := mapped.(*Pipeline); ok {
+			if Error = pipeline.Out(&mapped); Error != nil {
+				return false
+			}
+		}
+		if mapped == nil || IsString(mapped) || !IsIterable(mapped) {
+			result = append(result, mapped)
+			return true
+		}
+		result = append(result, NewIterable(mapped).ToArrayOfInterface()...)
+		Error = iteratorError(mapped)
+		return Error == nil
+	})
+	if Error == nil {
+		Error = err
+	}
+	if Error != nil {
+		return nil, Error
+	}
+	return result, nil
+}
+
 // Reduce folds the array into a single value
 func Reduce(value interface{}, callback func(result interface{}, element interface{}, index int) interface{}, initialOrNil interface{}) (interface{}, error) {
 	if !IsIterable(value) {
@@ -758,15 +841,14 @@ func Unshift(array interface{}, values ...interface{}) (interface{}, error) {
 	return append(append([]interface{}{}, values...), iterable.ToArrayOfInterface()...), nil
 }
 
-// Sort sorts an array given a compare function
+// Sort sorts an array given a compare function, equal elements keep their order
 func Sort(array interface{}, compareFunc func(a, b interface{}) bool) (interface{}, error) {
 	if !IsIterable(array) {
 		return nil, NotIterableError{array}
 	}
-	iterable := NewIterable(array)
-	sorter := &sorter{iterable, compareFunc}
-	sort.Sort(sorter)
-	return sorter.array.ToArrayOfInterface(), nil
+	sorter := &sorter{NewIterable(array).ToArrayOfInterface(), compareFunc}
+	sort.Stable(sorter)
+	return sorter.array, nil
 }
 
 // Chunk Creates an array of elements split into groups the length of size. If collection can’t be split evenly, the final chunk will be the remaining elements.
@@ -1008,6 +1090,17 @@ type IterableInterface interface {
 	ToArrayOfInterface() []interface{}
 }
 
+// Iterator is a lazy, forward only sequence of values, like a reader or a cursor.
+// Next advances to the next value, Value returns the current value
+// and Err returns the error that stopped the iteration, if any.
+// Channels and Iterators are consumed once; operations that need random access
+// read them entirely first.
+type Iterator interface {
+	Next() bool
+	Value() interface{}
+	Err() error
+}
+
 // Iterable implements IterableInterface
 type Iterable struct {
 	array  reflect.Value
@@ -1021,6 +1114,9 @@ func NewIterable(array interface{}) IterableInterface {
 	if a, ok := array.(IterableInterface); ok == true {
 		return a
 	}
+	if iterable := typedIterable(array); iterable != nil {
+		return iterable
+	}
 	switch t := array.(type) {
 	case string:
 		res := []rune{}
@@ -1028,8 +1124,21 @@ func NewIterable(array interface{}) IterableInterface {
 			res = append(res, char)
 		}
 		return &Iterable{array: reflect.ValueOf(res), length: len(res)}
+	case Iterator:
+		res := []interface{}{}
+		for t.Next() {
+			res = append(res, t.Value())
+		}
+		return interfaceIterable(res)
 	default:
 		arr := reflect.ValueOf(array)
+		if arr.Kind() == reflect.Chan {
+			res := []interface{}{}
+			for value, ok := arr.Recv(); ok; value, ok = arr.Recv() {
+				res = append(res, value.Interface())
+			}
+			return interfaceIterable(res)
+		}
 
 		return &Iterable{array: arr, length: arr.Len(), isMap: arr.Kind() == reflect.Map}
 	}
@@ -1051,7 +1160,6 @@ func (iterable *Iterable) At(index int) interface{} {
 	if iterable.isMap {
 		if iterable.keys == nil {
 			iterable.keys = iterable.array.MapKeys()
-			log.Print(iterable.keys)
 		}
 		return iterable.array.MapIndex(iterable.keys[index]).Interface()
 	}
@@ -1067,6 +1175,40 @@ func (iterable Iterable) ToArrayOfInterface() []interface{} {
 	return result
 }
 
+// each calls callback for each element of array, without reading lazy sources
+// (channels and Iterators) into memory. Iteration stops when callback returns false.
+func each(array interface{}, callback func(element interface{}, index int) bool) error {
+	if !IsIterable(array) {
+		return NotIterableError{array}
+	}
+	if iterator, ok := array.(Iterator); ok {
+		defer closeSource(iterator)
+		for i := 0; iterator.Next(); i++ {
+			if !callback(iterator.Value(), i) {
+				break
+			}
+		}
+		return iterator.Err()
+	}
+	if arr := reflect.ValueOf(array); arr.Kind() == reflect.Chan {
+		i := 0
+		for value, ok := arr.Recv(); ok; value, ok = arr.Recv() {
+			if !callback(value.Interface(), i) {
+				break
+			}
+			i++
+		}
+		return nil
+	}
+	iterable := NewIterable(array)
+	for i := 0; i < iterable.Length(); i++ {
+		if !callback(iterable.At(i), i) {
+			break
+		}
+	}
+	return nil
+}
+
 /*********************************/
 /*            ERRORS             */
 /*********************************/
@@ -1115,13 +1257,29 @@ func (cannotAssignError CannotAssignError) Error() string {
 
 // StepError discriminates a step error
 type StepError struct {
-	step   int
-	reason interface{}
+	step     int
+	reason   interface{}
+	attempts int
 }
 
 // Error returns a string
 func (stepError StepError) Error() string {
-	return fmt.Sprintf("Error at step %d : %#v ", stepError.step, stepError.reason)
+	message := fmt.Sprintf("Error at step %d : %#v ", stepError.step, stepError.reason)
+	if stepError.attempts > 1 {
+		message += fmt.Sprintf("after %d attempts", stepError.attempts)
+	}
+	return message
+}
+
+// Attempts returns the number of times the failing callback was called, more than 1 with Retry
+func (stepError StepError) Attempts() int {
+	return stepError.attempts
+}
+
+// Unwrap returns the error of the step
+func (stepError StepError) Unwrap() error {
+	err, _ := stepError.reason.(error)
+	return err
 }
 
 // NotAPointerError discriminate pointer errors
@@ -1134,48 +1292,42 @@ func (notAPointerError NotAPointerError) Error() string {
 	return fmt.Sprintf(" %#v should be a pointer ", notAPointerError.value)
 }
 
+// NotComparableError discriminates values that cannot be ordered
+type NotComparableError struct {
+	a interface{}
+	b interface{}
+}
+
+// Error returns a string
+func (notComparableError NotComparableError) Error() string {
+	return fmt.Sprintf("Cannot compare %#v with %#v .", notComparableError.a, notComparableError.b)
+}
+
 // sorter is used for array.Sort
 type sorter struct {
-	array       IterableInterface
+	array       []interface{}
 	compareFunc func(a, b interface{}) bool
 }
 
 // Len returns the length of the array
 func (s *sorter) Len() int {
-	return s.array.Length()
+	return len(s.array)
 }
 
 // Less compare 2 elements. if i is less than j return true ,else return false
 func (s *sorter) Less(i, j int) bool {
-	return s.compareFunc(s.array.At(i), s.array.At(j))
+	return s.compareFunc(s.array[i], s.array[j])
 }
 
 // Swap swaps 2 elements
 func (s *sorter) Swap(i, j int) {
-	a := s.array.ToArrayOfInterface()
-	a[i], a[j] = a[j], a[i]
-
-	s.array = NewIterable(a)
+	s.array[i], s.array[j] = s.array[j], s.array[i]
 }
 
 /*********************************/
 /*             HELPERS           */
 /*********************************/
 
-func convertSliceOfInterfaceToTypedSlice(from interface{}, to reflect.Type) (reflect.Value, error) {
-	arr := reflect.MakeSlice(to, 0, 0)
-	it := NewIterable(from)
-	for i := 0; i < it.Length(); i++ {
-		val := it.At(i)
-		// if arr is not of type []interface{} and []val cannot be assigned to arr
-		if reflect.TypeOf(arr.Interface()) != reflect.TypeOf([]interface{}{}) && !reflect.SliceOf(reflect.TypeOf(val)).AssignableTo(reflect.TypeOf(arr.Interface())) {
-			return valueOf(nil), CannotAppendError{arr.Interface(), val}
-		}
-		arr = reflect.Append(arr, valueOf(val))
-	}
-	return arr, nil
-}
-
 func valueOf(in interface{}) reflect.Value {
 	return reflect.ValueOf(in)
 }
@@ -1184,10 +1336,6 @@ func isMap(in interface{}) bool {
 	return reflect.TypeOf(in).Kind() == reflect.Map || (reflect.TypeOf(in).Kind() == reflect.Ptr && reflect.TypeOf(in).Elem().Kind() == reflect.Map)
 }
 
-func isSlice(value interface{}) bool {
-	return reflect.TypeOf(value).Kind() == reflect.Slice || (reflect.TypeOf(value).Kind() == reflect.Ptr && reflect.TypeOf(value).Elem().Kind() == reflect.Slice)
-}
-
 func isPointer(in interface{}) bool {
 	return reflect.TypeOf(in).Kind() == reflect.Ptr
 }
@@ -1196,10 +1344,179 @@ func canAssignTo(in, out interface{}) bool {
 	return reflect.TypeOf(in).AssignableTo(reflect.TypeOf(out)) || reflect.TypeOf(in).AssignableTo(reflect.TypeOf(out).Elem())
 }
 
-func makeMapFrom(from interface{}) reflect.Value {
-	if isPointer(from) {
-		return reflect.MakeMap(reflect.TypeOf(from).Elem())
+// structField is an exported field of a struct, fields of embedded structs included
+type structField struct {
+	name  string
+	index []int
+}
+
+// structFields returns the fields of a struct type named by the first of tags they have
+// or by their name. Fields tagged with "-" are ignored.
+func structFields(structType reflect.Type, tags ...string) []structField {
+	fields := []structField{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name := ""
+		for _, tag := range tags {
+			if value, ok := field.Tag.Lookup(tag); ok {
+				name = strings.Split(value, ",")[0]
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == "" {
+			for _, embedded := range structFields(field.Type, tags...) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{name, []int{i}})
 	}
-	return reflect.MakeMap(reflect.TypeOf(from))
+	return fields
+}
 
+// setString parses text into a string, boolean, number or encoding.TextUnmarshaler value
+func setString(value reflect.Value, text string) error {
+	if value.CanAddr() {
+		if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return unmarshaler.UnmarshalText([]byte(text))
+		}
+	}
+	switch {
+	case value.Kind() == reflect.String:
+		value.SetString(text)
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case isInt(value):
+		i, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case isUint(value):
+		u, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case value.Kind() == reflect.Ptr:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setString(value.Elem(), text)
+	case value.Kind() == reflect.Interface && value.NumMethod() == 0:
+		value.Set(reflect.ValueOf(text))
+	default:
+		return CannotAssignError{text, value.Interface()}
+	}
+	return nil
+}
+
+// isLazy returns true if value is a source that can only be read once
+func isLazy(value interface{}) bool {
+	if _, ok := value.(Iterator); ok {
+		return true
+	}
+	return value != nil && reflect.TypeOf(value).Kind() == reflect.Chan
+}
+
+// closeSource closes an Iterator holding a resource, such as database rows or a file,
+// when it is read partially
+func closeSource(source interface{}) {
+	if _, ok := source.(Iterator); !ok {
+		return
+	}
+	if closer, ok := source.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// iteratorError returns the error that stopped an Iterator source, if any
+func iteratorError(source interface{}) error {
+	if iterator, ok := source.(Iterator); ok {
+		return iterator.Err()
+	}
+	return nil
+}
+
+// compare orders numbers, strings and booleans.
+// It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func compare(a, b interface{}) (int, error) {
+	va, vb := valueOf(a), valueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return 0, NotComparableError{a, b}
+	}
+	switch {
+	case isInt(va) && isInt(vb):
+		return threeWay(va.Int() < vb.Int(), va.Int() > vb.Int()), nil
+	case isUint(va) && isUint(vb):
+		return threeWay(va.Uint() < vb.Uint(), va.Uint() > vb.Uint()), nil
+	case isNumber(va) && isNumber(vb):
+		x, y := toFloat(va), toFloat(vb)
+		return threeWay(x < y, x > y), nil
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return threeWay(va.String() < vb.String(), va.String() > vb.String()), nil
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return threeWay(!va.Bool() && vb.Bool(), va.Bool() && !vb.Bool()), nil
+	}
+	return 0, NotComparableError{a, b}
+}
+
+func threeWay(lower, greater bool) int {
+	switch {
+	case lower:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func isInt(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isNumber(value reflect.Value) bool {
+	return isInt(value) || isUint(value) || value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64
+}
+
+func toFloat(value reflect.Value) float64 {
+	switch {
+	case isInt(value):
+		return float64(value.Int())
+	case isUint(value):
+		return float64(value.Uint())
+	}
+	return value.Float()
 }
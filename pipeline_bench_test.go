@@ -0,0 +1,137 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/pipeline"
+)
+
+// Benchmarks report the allocations of each operator, run them with
+//
+//	go test -run NONE -bench . -benchmem
+
+const benchmarkSize = 1000
+
+// Celsius is not one of the slice types read without reflection
+type Celsius []int
+
+func benchmarkInputs() map[string]func() interface{} {
+	return map[string]func() interface{}{
+		"[]int": func() interface{} {
+			array := make([]int, benchmarkSize)
+			for i := range array {
+				array[i] = benchmarkSize - i
+			}
+			return array
+		},
+		"[]string": func() interface{} {
+			array := make([]string, benchmarkSize)
+			for i := range array {
+				array[i] = fmt.Sprint(benchmarkSize - i)
+			}
+			return array
+		},
+		"[]interface{}": func() interface{} {
+			array := make([]interface{}, benchmarkSize)
+			for i := range array {
+				array[i] = benchmarkSize - i
+			}
+			return array
+		},
+		"reflect": func() interface{} {
+			array := make(Celsius, benchmarkSize)
+			for i := range array {
+				array[i] = benchmarkSize - i
+			}
+			return array
+		},
+	}
+}
+
+func identity(element interface{}, index int) interface{} {
+	return element
+}
+
+func even(element interface{}, index int) bool {
+	return index%2 == 0
+}
+
+func lessString(a, b interface{}) bool {
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+var benchmarkOperators = map[string]func(p *pipeline.Pipeline) *pipeline.Pipeline{
+	"Map":    func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Map(identity) },
+	"Filter": func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Filter(even) },
+	"Reduce": func(p *pipeline.Pipeline) *pipeline.Pipeline {
+		return p.Reduce(func(result, element interface{}, index int) interface{} { return index }, 0)
+	},
+	"Sort":      func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Sort(lessString) },
+	"Head":      func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Head(benchmarkSize / 2) },
+	"Reverse":   func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Reverse() },
+	"Chunk":     func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Chunk(10) },
+	"TopK":      func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.TopK(10, lessString) },
+	"Map+First": func(p *pipeline.Pipeline) *pipeline.Pipeline { return p.Map(identity).First() },
+}
+
+func BenchmarkOperators(b *testing.B) {
+	for name, operator := range benchmarkOperators {
+		for input, source := range benchmarkInputs() {
+			b.Run(name+"/"+input, func(b *testing.B) {
+				array := source()
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					var result interface{}
+					if err := operator(pipeline.In(array)).Out(&result); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkOut(b *testing.B) {
+	for input, source := range benchmarkInputs() {
+		b.Run(input, func(b *testing.B) {
+			array := source()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var result []interface{}
+				if err := pipeline.In(array).Out(&result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	b.Run("[]interface{} to []int", func(b *testing.B) {
+		array := benchmarkInputs()["[]interface{}"]()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var result []int
+			if err := pipeline.In(array).Out(&result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
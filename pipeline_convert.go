@@ -0,0 +1,281 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// OutOptions are the conversions OutWith may apply besides rebuilding
+// slices, arrays, maps and pointers
+type OutOptions struct {
+	// ConvertNumbers converts numbers to other numeric types when no precision is lost,
+	// such as int to int64 or float64(3) to int
+	ConvertNumbers bool
+	// UnmarshalText converts strings to types implementing encoding.TextUnmarshaler
+	UnmarshalText bool
+	// Strict fails on the keys of a map matching no field of the struct it is converted to
+	Strict bool
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// OutWith sets output, a pointer, to the result of the pipeline. Nested slices, arrays, maps
+// and pointers are rebuilt to the type of output, so that the [][]interface{} of Zip can be
+// read into a [][]int. A ConversionError tells where the result could not be converted.
+//
+// Maps with string keys are converted to structs: keys are matched with the pipeline
+// or json tags of the fields, or their names regardless of case. Fields of embedded
+// structs are set as if they were fields of the struct, missing fields are set to
+// the value of their default tag if any:
+//
+//	type Server struct {
+//		Host string `pipeline:"host"`
+//		Port int    `json:"port" default:"80"`
+//	}
+func (pipeline *Pipeline) OutWith(output interface{}, options OutOptions) error {
+	if !isPointer(output) {
+		return NotAPointerError{output}
+	}
+	result, err := pipeline.run()
+	if err != nil {
+		return err
+	}
+	// lazy sources are consumed by Out itself
+	if isLazy(result) {
+		source := result
+		result = NewIterable(source).ToArrayOfInterface()
+		if err := iteratorError(source); err != nil {
+			return StepError{len(pipeline.commands) + 1, err, 1}
+		}
+	}
+	if result != nil && canAssignTo(result, output) {
+		valueOf(output).Elem().Set(valueOf(result))
+		return nil
+	}
+	// common slice types are converted without reflection
+	if assignSlice(result, output) {
+		return nil
+	}
+	value, err := convert(valueOf(result), reflect.TypeOf(output).Elem(), "", options)
+	if err != nil {
+		return err
+	}
+	valueOf(output).Elem().Set(value)
+	return nil
+}
+
+// convert returns value converted to target, path is the position of value in the result
+func convert(value reflect.Value, target reflect.Type, path string, options OutOptions) (reflect.Value, error) {
+	for value.IsValid() && value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(target), nil
+		}
+		return value, ConversionError{path, nil, target, ""}
+	}
+	if value.Type().AssignableTo(target) {
+		return value, nil
+	}
+	if options.UnmarshalText && value.Kind() == reflect.String && reflect.PtrTo(target).Implements(textUnmarshalerType) {
+		result := reflect.New(target)
+		if err := result.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value.String())); err != nil {
+			return value, ConversionError{path, value.Interface(), target, err.Error()}
+		}
+		return result.Elem(), nil
+	}
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Zero(target), convertNil(value, target, path)
+		}
+		if target.Kind() != reflect.Ptr {
+			return convert(value.Elem(), target, path, options)
+		}
+	}
+	switch target.Kind() {
+	case reflect.Ptr:
+		if value.Kind() == reflect.Ptr {
+			value = value.Elem()
+		}
+		element, err := convert(value, target.Elem(), path, options)
+		if err != nil {
+			return value, err
+		}
+		result := reflect.New(target.Elem())
+		result.Elem().Set(element)
+		return result, nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+			break
+		}
+		var result reflect.Value
+		if target.Kind() == reflect.Slice {
+			result = reflect.MakeSlice(target, value.Len(), value.Len())
+		} else if value.Len() == target.Len() {
+			result = reflect.New(target).Elem()
+		} else {
+			return value, ConversionError{path, value.Interface(), target, fmt.Sprintf("length %d", value.Len())}
+		}
+		for i := 0; i < value.Len(); i++ {
+			element, err := convert(value.Index(i), target.Elem(), fmt.Sprintf("%s[%d]", path, i), options)
+			if err != nil {
+				return value, err
+			}
+			result.Index(i).Set(element)
+		}
+		return result, nil
+	case reflect.Struct:
+		if value.Kind() == reflect.Map {
+			return convertStruct(value, target, path, options)
+		}
+	case reflect.Map:
+		if value.Kind() != reflect.Map {
+			break
+		}
+		result := reflect.MakeMapWithSize(target, value.Len())
+		iterator := value.MapRange()
+		for iterator.Next() {
+			at := fmt.Sprintf("%s[%#v]", path, iterator.Key().Interface())
+			key, err := convert(iterator.Key(), target.Key(), at, options)
+			if err != nil {
+				return value, err
+			}
+			element, err := convert(iterator.Value(), target.Elem(), at, options)
+			if err != nil {
+				return value, err
+			}
+			// distinct keys of value may convert to the same key, such as int64(1) and 1
+			if result.MapIndex(key).IsValid() {
+				return value, ConversionError{at, iterator.Key().Interface(), target.Key(), "another key converts to the same value"}
+			}
+			result.SetMapIndex(key, element)
+		}
+		return result, nil
+	}
+	// named types of the same kind, such as type Celsius float64
+	if value.Kind() == target.Kind() && value.Kind() <= reflect.Complex128 || value.Kind() == reflect.String && target.Kind() == reflect.String {
+		return value.Convert(target), nil
+	}
+	if options.ConvertNumbers && isNumber(value) && isNumber(reflect.Zero(target)) {
+		if result, ok := convertNumber(value, target); ok {
+			return result, nil
+		}
+	}
+	return value, ConversionError{path, value.Interface(), target, ""}
+}
+
+// convertStruct returns a struct of type target whose fields are set to the elements of a map
+func convertStruct(value reflect.Value, target reflect.Type, path string, options OutOptions) (reflect.Value, error) {
+	keys := map[string]reflect.Value{}
+	names := []string{}
+	for _, key := range value.MapKeys() {
+		name := key
+		for name.Kind() == reflect.Interface && !name.IsNil() {
+			name = name.Elem()
+		}
+		if name.Kind() != reflect.String {
+			return value, ConversionError{path, value.Interface(), target, fmt.Sprintf("key %#v is not a field name", key.Interface())}
+		}
+		keys[name.String()] = key
+		names = append(names, name.String())
+	}
+	sort.Strings(names)
+	result := reflect.New(target).Elem()
+	for _, field := range structFields(target, "pipeline", "json") {
+		name, found := field.name, false
+		if _, found = keys[name]; !found {
+			for _, candidate := range names {
+				if _, unused := keys[candidate]; unused && strings.EqualFold(candidate, field.name) {
+					name, found = candidate, true
+					break
+				}
+			}
+		}
+		at := fmt.Sprintf("%s[%q]", path, field.name)
+		if !found {
+			if text, ok := target.FieldByIndex(field.index).Tag.Lookup("default"); ok {
+				if err := setString(result.FieldByIndex(field.index), text); err != nil {
+					return value, ConversionError{at, text, target.FieldByIndex(field.index).Type, "default : " + err.Error()}
+				}
+			}
+			continue
+		}
+		element, err := convert(value.MapIndex(keys[name]), target.FieldByIndex(field.index).Type, fmt.Sprintf("%s[%q]", path, name), options)
+		if err != nil {
+			return value, err
+		}
+		result.FieldByIndex(field.index).Set(element)
+		delete(keys, name)
+	}
+	if options.Strict {
+		for _, name := range names {
+			if key, unknown := keys[name]; unknown {
+				return value, ConversionError{fmt.Sprintf("%s[%q]", path, name), value.MapIndex(key).Interface(), target, "unknown field"}
+			}
+		}
+	}
+	return result, nil
+}
+
+// convertNil returns an error unless target can be nil
+func convertNil(value reflect.Value, target reflect.Type, path string) error {
+	switch target.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+		return nil
+	}
+	return ConversionError{path, value.Interface(), target, ""}
+}
+
+// convertNumber converts value to target if converting the result back gives value
+func convertNumber(value reflect.Value, target reflect.Type) (reflect.Value, bool) {
+	result := value.Convert(target)
+	if result.Convert(value.Type()).Interface() != value.Interface() {
+		return value, false
+	}
+	// converting back does not catch a change of sign
+	if isInt(value) && isUint(result) && value.Int() < 0 || isUint(value) && isInt(result) && result.Int() < 0 {
+		return value, false
+	}
+	return result, true
+}
+
+// ConversionError is returned by Out when a part of the result cannot be converted to the output
+type ConversionError struct {
+	path   string
+	value  interface{}
+	target reflect.Type
+	reason string
+}
+
+// Error returns a string
+func (conversionError ConversionError) Error() string {
+	message := fmt.Sprintf("Cannot convert %#v to %s", conversionError.value, conversionError.target)
+	if conversionError.path != "" {
+		message += " at " + conversionError.path
+	}
+	if conversionError.reason != "" {
+		message += " : " + conversionError.reason
+	}
+	return message
+}
@@ -0,0 +1,172 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+// Level implements encoding.TextUnmarshaler
+type Level int
+
+func (level *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*level = 0
+	case "error":
+		*level = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func TestOutNested(t *testing.T) {
+	e := expect.New(t)
+	var pairs [][]int
+	err := pipeline.In([]interface{}{[]int{1, 2}, []int{3, 4}}).Zip().Out(&pairs)
+	e.Expect(err).ToBeNil()
+	e.Expect(pairs).ToEqual([][]int{{1, 3}, {2, 4}})
+
+	var byAge map[int][]Person
+	people := []Person{{30, "ann"}, {25, "bob"}, {30, "cid"}}
+	err = pipeline.In(people).GroupBy(func(el interface{}, i int) interface{} { return el.(Person).Age }).Out(&byAge)
+	e.Expect(err).ToBeNil()
+	e.Expect(byAge).ToEqual(map[int][]Person{30: {people[0], people[2]}, 25: {people[1]}})
+
+	var arrays [][2]string
+	err = pipeline.In([]interface{}{[]string{"a", "b"}}).Out(&arrays)
+	e.Expect(err).ToBeNil()
+	e.Expect(arrays).ToEqual([][2]string{{"a", "b"}})
+
+	var pointers []*Person
+	err = pipeline.In([]interface{}{people[0], &people[1], nil}).Out(&pointers)
+	e.Expect(err).ToBeNil()
+	e.Expect(*pointers[0]).ToEqual(people[0])
+	e.Expect(pointers[1]).ToEqual(&people[1])
+	e.Expect(pointers[2]).ToBeNil()
+}
+
+func TestOutConversionError(t *testing.T) {
+	e := expect.New(t)
+	var result []map[string][]int
+	err := pipeline.In([]interface{}{
+		map[string]interface{}{"a": []interface{}{1}},
+		map[string]interface{}{"a": []interface{}{1, "two"}},
+	}).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual(`Cannot convert "two" to int at [1]["a"][1]`)
+
+	var pair [3]int
+	err = pipeline.In([]interface{}{1, 2}).Out(&pair)
+	e.Expect(err.Error()).ToEqual(`Cannot convert []interface {}{1, 2} to [3]int : length 2`)
+
+	var number int
+	err = pipeline.In([]int{}).First().Out(&number)
+	e.Expect(err.Error()).ToEqual(`Cannot convert <nil> to int`)
+}
+
+func TestOutWith(t *testing.T) {
+	e := expect.New(t)
+	var result []int64
+	err := pipeline.In([]interface{}{1, 2.0, uint8(3)}).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	err = pipeline.In([]interface{}{1, 2.0, uint8(3)}).OutWith(&result, pipeline.OutOptions{ConvertNumbers: true})
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual([]int64{1, 2, 3})
+
+	for _, lossy := range []interface{}{2.5, -1} {
+		var unsigned []uint
+		err = pipeline.In([]interface{}{lossy}).OutWith(&unsigned, pipeline.OutOptions{ConvertNumbers: true})
+		e.Expect(err).Not().ToBeNil()
+	}
+
+	// keys converting to the same key do not overwrite each other
+	var counts map[int]int
+	err = pipeline.In(map[interface{}]int{1: 1, int64(1): 2}).OutWith(&counts, pipeline.OutOptions{ConvertNumbers: true})
+	conversionError := pipeline.ConversionError{}
+	e.Expect(errors.As(err, &conversionError)).ToBeTrue()
+	e.Expect(strings.HasSuffix(conversionError.Error(), "] : another key converts to the same value")).ToBeTrue()
+
+	var levels map[string]Level
+	options := pipeline.OutOptions{UnmarshalText: true}
+	err = pipeline.In(map[string]string{"db": "debug", "api": "error"}).OutWith(&levels, options)
+	e.Expect(err).ToBeNil()
+	e.Expect(levels).ToEqual(map[string]Level{"db": 0, "api": 1})
+	err = pipeline.In(map[string]string{"db": "trace"}).OutWith(&levels, options)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(strings.HasSuffix(err.Error(), `at ["db"] : unknown level "trace"`)).ToBeTrue()
+}
+
+func ExamplePipeline_OutWith() {
+	var rows [][]float64
+	err := pipeline.In([]interface{}{[]int{1, 2}, []int{3, 4}}).Zip().OutWith(&rows, pipeline.OutOptions{ConvertNumbers: true})
+	fmt.Print(rows, " ", err)
+	// Output: [[1 3] [2 4]] <nil>
+}
+
+type Endpoint struct {
+	Host string `pipeline:"host"`
+	Port int    `json:"port,omitempty" default:"80"`
+}
+
+type Service struct {
+	Endpoint
+	Name    string
+	Tags    []string `json:"tags"`
+	Timeout *int     `default:"30"`
+	secret  string
+}
+
+func TestOutStruct(t *testing.T) {
+	e := expect.New(t)
+	var services []Service
+	err := pipeline.In([]interface{}{
+		map[string]interface{}{"name": "api", "host": "10.0.0.1", "port": 8080, "tags": []interface{}{"web"}},
+		map[interface{}]interface{}{"Name": "db", "host": "10.0.0.2", "secret": "s"},
+	}).Out(&services)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(services)).ToEqual(2)
+	e.Expect(services[0].Name).ToEqual("api")
+	e.Expect(services[0].Endpoint).ToEqual(Endpoint{"10.0.0.1", 8080})
+	e.Expect(services[0].Tags).ToEqual([]string{"web"})
+	e.Expect(*services[0].Timeout).ToEqual(30)
+	e.Expect(services[1].Endpoint).ToEqual(Endpoint{"10.0.0.2", 80})
+	e.Expect(services[1].secret).ToEqual("")
+
+	var service Service
+	err = pipeline.In(map[string]interface{}{"name": "api", "port": "8080"}).Out(&service)
+	e.Expect(err.Error()).ToEqual(`Cannot convert "8080" to int at ["port"]`)
+
+	err = pipeline.In(map[string]interface{}{"name": "api", "secret": "s"}).OutWith(&service, pipeline.OutOptions{Strict: true})
+	e.Expect(err.Error()).ToEqual(`Cannot convert "s" to pipeline_test.Service at ["secret"] : unknown field`)
+}
+
+func ExamplePipeline_OutWith_struct() {
+	var endpoints []Endpoint
+	err := pipeline.In([]string{"a.local", "b.local"}).Map(func(el interface{}, i int) interface{} {
+		return map[string]interface{}{"host": el, "port": 8080 + i}
+	}).Out(&endpoints)
+	fmt.Print(endpoints, " ", err)
+	// Output: [{a.local 8080} {b.local 8081}] <nil>
+}
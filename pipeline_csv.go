@@ -0,0 +1,313 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CSVOptions configures FromCSV
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' by default
+	Comma rune
+	// Comment starts lines that are ignored, if not 0
+	Comment rune
+	// Header is true if the first record holds the names of the columns
+	Header bool
+	// Maps makes records map[string]string keyed by column name, it requires Header
+	Maps bool
+	// Target is a struct or a pointer to a struct records are decoded into.
+	// Columns are matched with the fields by `csv:"column"` tag or by name if Header is true,
+	// by position otherwise.
+	Target interface{}
+}
+
+/*********************************/
+/*            SOURCES            */
+/*********************************/
+
+// FromCSV returns a Pipeline reading the records of a CSV document lazily.
+// Records are []string unless options ask for maps or structs.
+func FromCSV(reader io.Reader, options CSVOptions) *Pipeline {
+	csvReader := csv.NewReader(reader)
+	if options.Comma != 0 {
+		csvReader.Comma = options.Comma
+	}
+	csvReader.Comment = options.Comment
+	return In(&csvIterator{reader: csvReader, options: options})
+}
+
+// csvIterator implements Iterator
+type csvIterator struct {
+	reader  *csv.Reader
+	options CSVOptions
+	header  []string
+	fields  []structField
+	record  int
+	value   interface{}
+	err     error
+}
+
+// Next reads the next record
+func (iterator *csvIterator) Next() bool {
+	if iterator.err != nil {
+		return false
+	}
+	if iterator.options.Maps && !iterator.options.Header {
+		iterator.err = CSVOptionsError{"Maps requires Header"}
+		return false
+	}
+	record, err := iterator.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		iterator.err = err
+		return false
+	}
+	if iterator.options.Header && iterator.header == nil {
+		iterator.header = record
+		return iterator.Next()
+	}
+	iterator.record++
+	iterator.value, iterator.err = iterator.decode(record)
+	return iterator.err == nil
+}
+
+// Value returns the current record
+func (iterator *csvIterator) Value() interface{} {
+	return iterator.value
+}
+
+// Err returns the error that stopped the iteration
+func (iterator *csvIterator) Err() error {
+	return iterator.err
+}
+
+func (iterator *csvIterator) decode(record []string) (interface{}, error) {
+	switch {
+	case iterator.options.Target != nil:
+		targetType := reflect.TypeOf(iterator.options.Target)
+		structType := targetType
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if structType.Kind() != reflect.Struct {
+			return nil, CannotAssignError{record, iterator.options.Target}
+		}
+		if iterator.fields == nil {
+			iterator.fields = structFields(structType, "csv")
+		}
+		target := reflect.New(structType)
+		for i, text := range record {
+			field, ok := iterator.field(i)
+			if !ok {
+				continue
+			}
+			if err := setString(target.Elem().FieldByIndex(field.index), text); err != nil {
+				return nil, DecodeError{iterator.record, field.name, err}
+			}
+		}
+		if targetType.Kind() == reflect.Ptr {
+			return target.Interface(), nil
+		}
+		return target.Elem().Interface(), nil
+	case iterator.options.Maps:
+		result := map[string]string{}
+		for i, text := range record {
+			if i < len(iterator.header) {
+				result[iterator.header[i]] = text
+			}
+		}
+		return result, nil
+	}
+	return record, nil
+}
+
+// field returns the struct field of column i
+func (iterator *csvIterator) field(i int) (structField, bool) {
+	if iterator.header == nil {
+		if i < len(iterator.fields) {
+			return iterator.fields[i], true
+		}
+		return structField{}, false
+	}
+	if i >= len(iterator.header) {
+		return structField{}, false
+	}
+	for _, field := range iterator.fields {
+		if strings.EqualFold(field.name, iterator.header[i]) {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
+
+/*********************************/
+/*             SINKS             */
+/*********************************/
+
+// ToCSV executes the pipeline and writes the elements of the result as CSV records, one by one.
+// Structs and maps are written after a header row, []string records as they are.
+// Records after a header must be structs of the same type, or maps. The header of maps is
+// made of the keys of the first one, an UnknownColumnError is returned for a later map
+// with other keys.
+func (pipeline *Pipeline) ToCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	var columns []string
+	var fields []structField
+	// recordType is the type of the records after the header row
+	var recordType reflect.Type
+	err := pipeline.sink(func(element interface{}, index int) error {
+		value := reflect.ValueOf(element)
+		for value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+			value = value.Elem()
+		}
+		record := []string{}
+		// maps of any type fill the columns of a map header
+		if recordType != nil && (value.Kind() != recordType.Kind() || value.Kind() == reflect.Struct && value.Type() != recordType) {
+			return RecordTypeError{index + 1, recordType, reflect.TypeOf(element)}
+		}
+		switch {
+		case value.Kind() == reflect.Struct:
+			if recordType == nil {
+				recordType = value.Type()
+				fields = structFields(value.Type(), "csv")
+				for _, field := range fields {
+					columns = append(columns, field.name)
+				}
+				if err := csvWriter.Write(columns); err != nil {
+					return err
+				}
+			}
+			for _, field := range fields {
+				record = append(record, formatCSV(value.FieldByIndex(field.index).Interface()))
+			}
+		case value.Kind() == reflect.Map:
+			cells := map[string]string{}
+			for _, key := range value.MapKeys() {
+				cells[fmt.Sprint(key.Interface())] = formatCSV(value.MapIndex(key).Interface())
+			}
+			if recordType == nil {
+				recordType = value.Type()
+				for column := range cells {
+					columns = append(columns, column)
+				}
+				sort.Strings(columns)
+				if err := csvWriter.Write(columns); err != nil {
+					return err
+				}
+			}
+			for _, column := range columns {
+				record = append(record, cells[column])
+			}
+			// the header is written first, keys missing from it would be lost
+			unknown := []string{}
+			for column := range cells {
+				if i := sort.SearchStrings(columns, column); i == len(columns) || columns[i] != column {
+					unknown = append(unknown, column)
+				}
+			}
+			if len(unknown) > 0 {
+				sort.Strings(unknown)
+				return UnknownColumnError{index + 1, unknown}
+			}
+		case value.Kind() == reflect.Slice && !IsString(element):
+			iterable := NewIterable(element)
+			for i := 0; i < iterable.Length(); i++ {
+				record = append(record, formatCSV(iterable.At(i)))
+			}
+		default:
+			record = append(record, formatCSV(element))
+		}
+		return csvWriter.Write(record)
+	})
+	// the records written before an error are flushed too
+	csvWriter.Flush()
+	if err != nil {
+		return err
+	}
+	return csvWriter.Error()
+}
+
+func formatCSV(value interface{}) string {
+	if marshaler, ok := value.(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// DecodeError discriminates a value that cannot be decoded into a field
+type DecodeError struct {
+	record int
+	field  string
+	reason error
+}
+
+// Error returns a string
+func (decodeError DecodeError) Error() string {
+	return fmt.Sprintf("Cannot decode field %s of record %d : %s", decodeError.field, decodeError.record, decodeError.reason)
+}
+
+// CSVOptionsError discriminates invalid CSVOptions
+type CSVOptionsError struct {
+	reason string
+}
+
+// Error returns a string
+func (csvOptionsError CSVOptionsError) Error() string {
+	return fmt.Sprintf("Invalid CSV options : %s", csvOptionsError.reason)
+}
+
+// RecordTypeError discriminates a record whose type differs from the previous ones
+type RecordTypeError struct {
+	record   int
+	expected reflect.Type
+	actual   reflect.Type
+}
+
+// Error returns a string
+func (recordTypeError RecordTypeError) Error() string {
+	return fmt.Sprintf("Record %d is a %s, expected a %s", recordTypeError.record, recordTypeError.actual, recordTypeError.expected)
+}
+
+// UnknownColumnError discriminates a map record with keys that are not in the header
+type UnknownColumnError struct {
+	record  int
+	columns []string
+}
+
+// Error returns a string
+func (unknownColumnError UnknownColumnError) Error() string {
+	return fmt.Sprintf("Record %d has columns missing from the header : %s", unknownColumnError.record, strings.Join(unknownColumnError.columns, ", "))
+}
@@ -0,0 +1,119 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+const productsCSV = `id,name,category,price
+0,Iphone 6,0,500
+1,HTC one,0,300
+2,Apple Watch,1,600
+`
+
+type CSVProduct struct {
+	ID       int    `csv:"id"`
+	Name     string `csv:"name"`
+	Category int    `csv:"category"`
+	Price    float64
+}
+
+func TestFromCSV(t *testing.T) {
+	e := expect.New(t)
+	var records [][]string
+	err := pipeline.FromCSV(strings.NewReader(productsCSV), pipeline.CSVOptions{}).Out(&records)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(records)).ToEqual(4)
+	e.Expect(records[1][1]).ToEqual("Iphone 6")
+
+	var rows []map[string]string
+	err = pipeline.FromCSV(strings.NewReader(productsCSV), pipeline.CSVOptions{Header: true, Maps: true}).
+		Filter(func(el interface{}, i int) bool {
+			return el.(map[string]string)["category"] == "0"
+		}).Out(&rows)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(rows)).ToEqual(2)
+	e.Expect(rows[1]["name"]).ToEqual("HTC one")
+
+	var products []CSVProduct
+	err = pipeline.FromCSV(strings.NewReader(productsCSV), pipeline.CSVOptions{Header: true, Target: CSVProduct{}}).Out(&products)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(products)).ToEqual(3)
+	e.Expect(products[2].Price).ToEqual(600.0)
+
+	var pointers []*CSVProduct
+	err = pipeline.FromCSV(strings.NewReader("1;Pen;2;1.5\n"), pipeline.CSVOptions{Comma: ';', Target: &CSVProduct{}}).Out(&pointers)
+	e.Expect(err).ToBeNil()
+	e.Expect(pointers[0].Name).ToEqual("Pen")
+
+	err = pipeline.FromCSV(strings.NewReader("id\nabc\n"), pipeline.CSVOptions{Header: true, Target: CSVProduct{}}).Out(&products)
+	e.Expect(err).Not().ToBeNil()
+
+	err = pipeline.FromCSV(strings.NewReader(productsCSV), pipeline.CSVOptions{Maps: true}).Out(&rows)
+	e.Expect(err).Not().ToBeNil()
+	csvOptionsError := pipeline.CSVOptionsError{}
+	e.Expect(errors.As(err, &csvOptionsError)).ToBeTrue()
+}
+
+func TestToCSV(t *testing.T) {
+	e := expect.New(t)
+	buffer := &bytes.Buffer{}
+	err := pipeline.FromCSV(strings.NewReader(productsCSV), pipeline.CSVOptions{Header: true, Target: CSVProduct{}}).ToCSV(buffer)
+	e.Expect(err).ToBeNil()
+	e.Expect(buffer.String()).ToEqual("id,name,category,Price\n0,Iphone 6,0,500\n1,HTC one,0,300\n2,Apple Watch,1,600\n")
+
+	buffer.Reset()
+	err = pipeline.In([]map[string]interface{}{{"b": 1, "a": "x"}, {"a": "y"}}).ToCSV(buffer)
+	e.Expect(err).ToBeNil()
+	e.Expect(buffer.String()).ToEqual("a,b\nx,1\ny,\n")
+
+	// keys of later maps must be in the header
+	buffer.Reset()
+	err = pipeline.In([]map[string]interface{}{{"a": "x"}, {"a": "y", "d": 2, "c": 1}}).ToCSV(buffer)
+	unknownColumnError := pipeline.UnknownColumnError{}
+	e.Expect(errors.As(err, &unknownColumnError)).ToBeTrue()
+	e.Expect(unknownColumnError.Error()).ToEqual("Record 2 has columns missing from the header : c, d")
+	e.Expect(buffer.String()).ToEqual("a\nx\n")
+
+	// records written before an error are flushed
+	buffer.Reset()
+	err = pipeline.In([]interface{}{Product{ID: 1, Name: "Pen"}, CSVProduct{ID: 2}}).ToCSV(buffer)
+	recordTypeError := pipeline.RecordTypeError{}
+	e.Expect(errors.As(err, &recordTypeError)).ToBeTrue()
+	e.Expect(recordTypeError.Error()).ToEqual("Record 2 is a pipeline_test.CSVProduct, expected a pipeline_test.Product")
+	e.Expect(buffer.String()).ToEqual("ID,Name,CategoryID,Price\n1,Pen,0,0\n")
+}
+
+func ExamplePipeline_ToCSV() {
+	err := pipeline.In(Products{{0, "Iphone 6", 0, 500}, {3, "ThinkPad", 2, 250}}).
+		Filter(func(el interface{}, i int) bool { return el.(Product).Price < 300 }).
+		ToCSV(os.Stdout)
+	fmt.Print(err)
+	// Output:
+	// ID,Name,CategoryID,Price
+	// 3,ThinkPad,2,250
+	// <nil>
+}
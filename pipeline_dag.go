@@ -0,0 +1,226 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NodeFunc returns the pipeline of a node of a Graph. in starts with the output of the
+// first input of the node, sides are the outputs of the other inputs in order.
+type NodeFunc func(in *Pipeline, sides ...interface{}) *Pipeline
+
+// Graph is a set of named pipelines, nodes, reading the outputs of other nodes.
+// Nodes whose inputs are ready run concurrently:
+//
+//	outputs, err := pipeline.NewGraph().
+//		Source("orders", orders).
+//		Source("refunds", refunds).
+//		Concat("movements", "orders", "refunds").
+//		Node("total", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+//			return in.Reduce(sum, 0)
+//		}, "movements").
+//		Run()
+type Graph struct {
+	nodes []*graphNode
+}
+
+type graphNode struct {
+	name   string
+	inputs []string
+	body   NodeFunc
+	source interface{}
+}
+
+// NewGraph returns an empty Graph
+func NewGraph() *Graph {
+	return &Graph{}
+}
+
+// Source adds a node named name whose output is source
+func (graph *Graph) Source(name string, source interface{}) *Graph {
+	graph.nodes = append(graph.nodes, &graphNode{name: name, source: source})
+	return graph
+}
+
+// Node adds a node named name running the pipeline returned by body over the outputs of inputs
+func (graph *Graph) Node(name string, body NodeFunc, inputs ...string) *Graph {
+	graph.nodes = append(graph.nodes, &graphNode{name: name, inputs: inputs, body: body})
+	return graph
+}
+
+// Union adds a node returning the unique elements of the outputs of inputs
+func (graph *Graph) Union(name string, inputs ...string) *Graph {
+	return graph.Node(name, func(in *Pipeline, sides ...interface{}) *Pipeline {
+		return in.Union(sides...)
+	}, inputs...)
+}
+
+// Concat adds a node returning the elements of the outputs of inputs, one after the other
+func (graph *Graph) Concat(name string, inputs ...string) *Graph {
+	return graph.Node(name, func(in *Pipeline, sides ...interface{}) *Pipeline {
+		return in.Concat(sides...)
+	}, inputs...)
+}
+
+// Query adds a node running a query over the outputs of inputs, the nth ? of the query
+// being the output of the nth input. Queries can join inputs:
+//
+//	graph.Query("report", "SELECT o.id, c.name FROM ? AS o JOIN ? AS c ON o.customer = c.id", "orders", "customers")
+func (graph *Graph) Query(name string, sql string, inputs ...string) *Graph {
+	return graph.Node(name, func(in *Pipeline, sides ...interface{}) *Pipeline {
+		return in.Op(func(first interface{}) (interface{}, error) {
+			var result interface{}
+			err := Query(sql, append([]interface{}{first}, sides...)...).Out(&result)
+			return result, err
+		})
+	}, inputs...)
+}
+
+// validate returns the nodes in an order where each node comes after its inputs
+func (graph *Graph) validate() ([]*graphNode, error) {
+	names := map[string]bool{}
+	elements := []interface{}{}
+	for _, node := range graph.nodes {
+		if names[node.name] {
+			return nil, GraphError{node.name, "duplicate node"}
+		}
+		names[node.name] = true
+		elements = append(elements, node)
+	}
+	for _, node := range graph.nodes {
+		if node.body != nil && len(node.inputs) == 0 {
+			return nil, GraphError{node.name, "no input"}
+		}
+		for _, input := range node.inputs {
+			if !names[input] {
+				return nil, GraphError{node.name, fmt.Sprintf("unknown input %s", input)}
+			}
+		}
+	}
+	sorted, err := TopoSort(elements, func(element interface{}, index int) interface{} {
+		return element.(*graphNode).name
+	}, func(element interface{}, index int) interface{} {
+		return element.(*graphNode).inputs
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*graphNode{}
+	for _, element := range sorted.([]interface{}) {
+		nodes = append(nodes, element.(*graphNode))
+	}
+	return nodes, nil
+}
+
+// Run runs the nodes of the graph and returns their outputs by name. A node runs as soon as
+// its inputs are ready, nodes reading the output of a failed node do not run. A node whose
+// function panics or returns nil fails.
+// The error joins the NodeErrors of the failed nodes, outputs of the other nodes are returned.
+func (graph *Graph) Run() (map[string]interface{}, error) {
+	nodes, err := graph.validate()
+	if err != nil {
+		return nil, err
+	}
+	type state struct {
+		done   chan struct{}
+		output interface{}
+		err    error
+		failed bool
+	}
+	states := map[string]*state{}
+	for _, node := range nodes {
+		states[node.name] = &state{done: make(chan struct{})}
+	}
+	wait := sync.WaitGroup{}
+	for _, node := range nodes {
+		wait.Add(1)
+		go func(node *graphNode, current *state) {
+			defer wait.Done()
+			defer close(current.done)
+			inputs := []interface{}{}
+			for _, name := range node.inputs {
+				input := states[name]
+				<-input.done
+				if input.failed {
+					current.failed = true
+					return
+				}
+				// nodes reading the same output get their own copy of the slice, its elements are shared
+				inputs = append(inputs, copySlice(input.output))
+			}
+			// a panicking node fails like a node returning an error
+			defer func() {
+				if value := recover(); value != nil {
+					current.err = fmt.Errorf("panic : %v", value)
+				}
+				current.failed = current.err != nil
+			}()
+			if node.body == nil {
+				current.err = In(node.source).Out(&current.output)
+				return
+			}
+			pipeline := node.body(In(inputs[0]), inputs[1:]...)
+			if pipeline == nil {
+				current.err = fmt.Errorf("node function returned a nil pipeline")
+				return
+			}
+			current.err = pipeline.Out(&current.output)
+		}(node, states[node.name])
+	}
+	wait.Wait()
+	outputs := map[string]interface{}{}
+	errs := []error{}
+	for _, node := range nodes {
+		current := states[node.name]
+		if current.err != nil {
+			errs = append(errs, NodeError{node.name, current.err})
+		} else if !current.failed {
+			outputs[node.name] = current.output
+		}
+	}
+	return outputs, errors.Join(errs...)
+}
+
+// GraphError discriminates an invalid Graph
+type GraphError struct {
+	node   string
+	reason string
+}
+
+// Error returns a string
+func (graphError GraphError) Error() string {
+	return fmt.Sprintf("Invalid node %s : %s", graphError.node, graphError.reason)
+}
+
+// NodeError is the error of a node of a Graph
+type NodeError struct {
+	node string
+	err  error
+}
+
+// Error returns a string
+func (nodeError NodeError) Error() string {
+	return fmt.Sprintf("Error in node %s : %s", nodeError.node, nodeError.err)
+}
+
+// Unwrap returns the error of the node
+func (nodeError NodeError) Unwrap() error {
+	return nodeError.err
+}
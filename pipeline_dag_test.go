@@ -0,0 +1,134 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func TestGraph(t *testing.T) {
+	e := expect.New(t)
+	refunds := make(chan int, 2)
+	refunds <- -20
+	refunds <- -5
+	close(refunds)
+	threshold := func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+		limit := sides[0].([]int)[0]
+		return in.Filter(func(el interface{}, i int) bool { return el.(int) > limit })
+	}
+	outputs, err := pipeline.NewGraph().
+		Source("orders", []int{100, 20, 100}).
+		Source("refunds", refunds).
+		Source("limit", []int{50}).
+		Concat("movements", "orders", "refunds").
+		Union("amounts", "orders", "refunds").
+		Node("total", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+			return in.Reduce(sum, 0)
+		}, "movements").
+		Node("large", threshold, "orders", "limit").
+		Run()
+	e.Expect(err).ToBeNil()
+	e.Expect(outputs["movements"]).ToEqual([]interface{}{100, 20, 100, -20, -5})
+	e.Expect(outputs["amounts"]).ToEqual([]interface{}{100, 20, -20, -5})
+	e.Expect(outputs["total"]).ToEqual(195)
+	e.Expect(outputs["large"]).ToEqual([]interface{}{100, 100})
+	e.Expect(len(outputs)).ToEqual(7)
+}
+
+func TestGraphQuery(t *testing.T) {
+	e := expect.New(t)
+	outputs, err := pipeline.NewGraph().
+		Source("items", items).
+		Source("sales", []Sale{{1, 2}, {1, 1}, {2, 5}}).
+		Query("revenue", "SELECT i.Name, SUM(s.Quantity * i.Price) AS revenue FROM ? i JOIN ? AS s ON s.ItemID = i.ID GROUP BY i.Name ORDER BY revenue DESC", "items", "sales").
+		Run()
+	e.Expect(err).ToBeNil()
+	var rows []map[string]interface{}
+	e.Expect(pipeline.In(outputs["revenue"]).Out(&rows)).ToBeNil()
+	e.Expect(len(rows)).ToEqual(2)
+}
+
+func TestGraphErrors(t *testing.T) {
+	e := expect.New(t)
+	identity := func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline { return in }
+	for graph, expected := range map[*pipeline.Graph]string{
+		pipeline.NewGraph().Source("a", nil).Source("a", nil):                 "Invalid node a : duplicate node",
+		pipeline.NewGraph().Node("a", identity, "b"):                          "Invalid node a : unknown input b",
+		pipeline.NewGraph().Node("a", identity):                               "Invalid node a : no input",
+		pipeline.NewGraph().Node("a", identity, "b").Node("b", identity, "a"): "Cycle detected : a -> b -> a",
+	} {
+		_, err := graph.Run()
+		e.Expect(err).Not().ToBeNil()
+		e.Expect(err.Error()).ToEqual(expected)
+	}
+
+	failure := errors.New("failure")
+	outputs, err := pipeline.NewGraph().
+		Source("numbers", []int{1, 2}).
+		Node("failing", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+			return in.Op(func(in interface{}) (interface{}, error) { return nil, failure })
+		}, "numbers").
+		Node("downstream", identity, "failing").
+		Node("reversed", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline { return in.Reverse() }, "numbers").
+		Run()
+	var nodeError pipeline.NodeError
+	e.Expect(errors.As(err, &nodeError)).ToBeTrue()
+	e.Expect(strings.HasPrefix(err.Error(), "Error in node failing : Error at step 1 ")).ToBeTrue()
+	e.Expect(outputs["reversed"]).ToEqual([]interface{}{2, 1})
+	_, ok := outputs["downstream"]
+	e.Expect(ok).ToBeFalse()
+}
+
+func TestGraphNodePanics(t *testing.T) {
+	e := expect.New(t)
+	outputs, err := pipeline.NewGraph().
+		Source("numbers", []int{1, 2}).
+		Node("panicking", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+			panic("boom")
+		}, "numbers").
+		Node("nil", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+			return nil
+		}, "numbers").
+		Node("downstream", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline { return in }, "panicking").
+		Node("reversed", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline { return in.Reverse() }, "numbers").
+		Run()
+	var nodeError pipeline.NodeError
+	e.Expect(errors.As(err, &nodeError)).ToBeTrue()
+	e.Expect(err.Error()).ToEqual("Error in node panicking : panic : boom\nError in node nil : node function returned a nil pipeline")
+	e.Expect(outputs["reversed"]).ToEqual([]interface{}{2, 1})
+	_, ok := outputs["downstream"]
+	e.Expect(ok).ToBeFalse()
+}
+
+func ExampleGraph() {
+	outputs, err := pipeline.NewGraph().
+		Source("orders", []int{120, 45}).
+		Source("refunds", []int{-30}).
+		Concat("movements", "orders", "refunds").
+		Node("balance", func(in *pipeline.Pipeline, sides ...interface{}) *pipeline.Pipeline {
+			return in.Reduce(sum, 0)
+		}, "movements").
+		Run()
+	fmt.Print(outputs["movements"], " ", outputs["balance"], " ", err)
+	// Output: [120 45 -30] 135 <nil>
+}
@@ -0,0 +1,100 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorMode tells what a pipeline does when the callback of MapE or ParallelMapE
+// fails for an element
+type ErrorMode int
+
+const (
+	// Fail stops the pipeline with a StepError, it is the default
+	Fail ErrorMode = iota
+	// Skip removes the failing elements from the result
+	Skip
+	// Collect removes the failing elements from the result and keeps them as dead letters
+	Collect
+)
+
+// String returns the name of the mode
+func (mode ErrorMode) String() string {
+	switch mode {
+	case Skip:
+		return "Skip"
+	case Collect:
+		return "Collect"
+	}
+	return "Fail"
+}
+
+// DeadLetter is an element removed from the result of a pipeline collecting its errors
+type DeadLetter struct {
+	// Step is the number of the step, starting at 1 as in StepError
+	Step int
+	// Index is the index of the element in the input of the step
+	Index int
+	// Value is the element
+	Value interface{}
+	// Err is the last error of the callback
+	Err error
+}
+
+// Error returns a string
+func (letter DeadLetter) Error() string {
+	return fmt.Sprintf("Error at step %d, element %d : %s", letter.Step, letter.Index, letter.Err)
+}
+
+// Unwrap returns the error of the callback
+func (letter DeadLetter) Unwrap() error {
+	return letter.Err
+}
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// OnError sets what the pipeline does when an element fails in MapE or ParallelMapE,
+// so that a few bad records do not stop a whole job:
+//
+//	p := pipeline.In(records).OnError(pipeline.Collect).MapE(parse)
+//	err := p.Out(&parsed)
+//	// p.DeadLetters() are the records parse failed on, p.Err() joins their errors
+//
+// Other steps fail the pipeline whatever the mode.
+func (pipeline *Pipeline) OnError(mode ErrorMode) *Pipeline {
+	pipeline.onError = mode
+	return pipeline
+}
+
+// DeadLetters returns the elements removed by the last run of a pipeline collecting
+// its errors, in the order of the steps and of the elements
+func (pipeline *Pipeline) DeadLetters() []DeadLetter {
+	return append([]DeadLetter{}, pipeline.deadLetters...)
+}
+
+// Err joins the dead letters of the last run, it is nil if there are none
+func (pipeline *Pipeline) Err() error {
+	errs := []error{}
+	for _, letter := range pipeline.deadLetters {
+		errs = append(errs, letter)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,108 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func parseInt(el interface{}, i int) (interface{}, error) {
+	return strconv.Atoi(el.(string))
+}
+
+func TestOnErrorCollect(t *testing.T) {
+	e := expect.New(t)
+	p := pipeline.In([]string{"1", "x", "3", "", "5"}).
+		OnError(pipeline.Collect).
+		MapE(parseInt).
+		MapE(func(el interface{}, i int) (interface{}, error) {
+			if el.(int) > 4 {
+				return nil, fmt.Errorf("%d is too large", el)
+			}
+			return el, nil
+		})
+	var result []int
+	e.Expect(p.Out(&result)).ToBeNil()
+	e.Expect(result).ToEqual([]int{1, 3})
+	letters := p.DeadLetters()
+	e.Expect(len(letters)).ToEqual(3)
+	e.Expect(letters[0].Step).ToEqual(1)
+	e.Expect(letters[0].Index).ToEqual(1)
+	e.Expect(letters[0].Value).ToEqual("x")
+	e.Expect(letters[1].Index).ToEqual(3)
+	e.Expect(letters[2].Step).ToEqual(2)
+	e.Expect(letters[2].Index).ToEqual(2)
+	e.Expect(letters[2].Value).ToEqual(5)
+	numError := &strconv.NumError{}
+	e.Expect(errors.As(p.Err(), &numError)).ToBeTrue()
+	lines := strings.Split(p.Err().Error(), "\n")
+	e.Expect(len(lines)).ToEqual(3)
+	e.Expect(lines[2]).ToEqual("Error at step 2, element 2 : 5 is too large")
+
+	// dead letters are the ones of the last run
+	p = pipeline.In([]string{"1"}).OnError(pipeline.Collect).MapE(parseInt)
+	e.Expect(p.Out(&result)).ToBeNil()
+	e.Expect(len(p.DeadLetters())).ToEqual(0)
+	e.Expect(p.Err()).ToBeNil()
+}
+
+func TestOnErrorSkip(t *testing.T) {
+	e := expect.New(t)
+	p := pipeline.In([]string{"1", "x", "3"}).OnError(pipeline.Skip).ParallelMapE(2, parseInt)
+	var result []int
+	e.Expect(p.Out(&result)).ToBeNil()
+	e.Expect(result).ToEqual([]int{1, 3})
+	e.Expect(len(p.DeadLetters())).ToEqual(0)
+	e.Expect(p.Err()).ToBeNil()
+
+	err := pipeline.In([]string{"1", "x", "3"}).OnError(pipeline.Fail).MapE(parseInt).Out(&result)
+	elementError := pipeline.ElementError{}
+	e.Expect(errors.As(err, &elementError)).ToBeTrue()
+	e.Expect(pipeline.Collect.String()).ToEqual("Collect")
+}
+
+func TestOnErrorWithPolicies(t *testing.T) {
+	e := expect.New(t)
+	clock := &fakeClock{}
+	p := pipeline.In([]string{"1", "x"}).
+		OnError(pipeline.Collect).
+		MapE(parseInt, pipeline.Retry(3, time.Second), pipeline.WithClock(clock))
+	var result []int
+	e.Expect(p.Out(&result)).ToBeNil()
+	e.Expect(result).ToEqual([]int{1})
+	e.Expect(len(clock.sleeps)).ToEqual(2)
+	e.Expect(len(p.DeadLetters())).ToEqual(1)
+}
+
+func ExamplePipeline_OnError() {
+	p := pipeline.In([]string{"7", "seven", "8"}).OnError(pipeline.Collect).MapE(parseInt)
+	var result []int
+	err := p.Out(&result)
+	fmt.Println(result, err)
+	fmt.Println(p.Err())
+	// Output:
+	// [7 8] <nil>
+	// Error at step 1, element 1 : strconv.Atoi: parsing "seven": invalid syntax
+}
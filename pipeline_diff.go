@@ -0,0 +1,297 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeKind is the kind of a Change
+type ChangeKind int
+
+const (
+	// Unchanged elements are in both collections and are equal
+	Unchanged ChangeKind = iota
+	// Added elements are only in the new collection
+	Added
+	// Removed elements are only in the old collection
+	Removed
+	// Changed elements are in both collections but are not equal
+	Changed
+)
+
+// String returns a string
+func (kind ChangeKind) String() string {
+	switch kind {
+	case Unchanged:
+		return "unchanged"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(kind))
+}
+
+// Change is an entry of the diff between an old and a new collection.
+// OldIndex is -1 for added elements and NewIndex is -1 for removed elements.
+type Change struct {
+	Kind     ChangeKind
+	Key      interface{}
+	Old      interface{}
+	New      interface{}
+	OldIndex int
+	NewIndex int
+}
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// Diff compares a collection with a newer version of it, matching elements by key
+func (pipeline *Pipeline) Diff(other interface{}, keyFn func(element interface{}, index int) interface{}, eqFn func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("Diff", func() (interface{}, error) {
+		return Diff(pipeline.in, other, keyFn, eqFn)
+	}, other, keyFn, eqFn)
+}
+
+// DiffSequence compares a sequence with a newer version of it, element by element
+func (pipeline *Pipeline) DiffSequence(other interface{}, eqFn func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("DiffSequence", func() (interface{}, error) {
+		return DiffSequence(pipeline.in, other, eqFn)
+	}, other, eqFn)
+}
+
+// ApplyPatch replays the changes of a diff onto a collection
+func (pipeline *Pipeline) ApplyPatch(patch interface{}, keyFn func(element interface{}, index int) interface{}) *Pipeline {
+	return pipeline.push("ApplyPatch", func() (interface{}, error) {
+		return ApplyPatch(pipeline.in, patch, keyFn)
+	}, patch, keyFn)
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// Diff compares array with other, a newer version of it. Elements are matched by the key
+// returned by keyFn and compared with eqFn, or reflect.DeepEqual if eqFn is nil.
+// It returns a []Change listing the elements of array in order then the added elements of other.
+func Diff(array interface{}, other interface{}, keyFn func(element interface{}, index int) interface{}, eqFn func(a, b interface{}) bool) (interface{}, error) {
+	for _, collection := range []interface{}{array, other} {
+		if !IsIterable(collection) {
+			return nil, NotIterableError{collection}
+		}
+	}
+	if eqFn == nil {
+		eqFn = reflect.DeepEqual
+	}
+	olds, news := NewIterable(array), NewIterable(other)
+	oldKeys, err := keysOf(olds, keyFn)
+	if err != nil {
+		return nil, err
+	}
+	newKeys, err := keysOf(news, keyFn)
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[interface{}]int{}
+	for i, key := range newKeys {
+		if !hasKey(indexes, key) {
+			indexes[key] = i
+		}
+	}
+	matched := map[int]bool{}
+	result := []Change{}
+	for i, key := range oldKeys {
+		j, ok := indexes[key]
+		switch {
+		case !ok || matched[j]:
+			result = append(result, Change{Removed, key, olds.At(i), nil, i, -1})
+		case eqFn(olds.At(i), news.At(j)):
+			result = append(result, Change{Unchanged, key, olds.At(i), news.At(j), i, j})
+		default:
+			result = append(result, Change{Changed, key, olds.At(i), news.At(j), i, j})
+		}
+		if ok {
+			matched[j] = true
+		}
+	}
+	for j, key := range newKeys {
+		if !matched[j] {
+			result = append(result, Change{Added, key, nil, news.At(j), -1, j})
+		}
+	}
+	return result, nil
+}
+
+// DiffSequence returns the shortest edit script turning array into other as a []Change,
+// based on their longest common subsequence. Elements are compared with eqFn,
+// or reflect.DeepEqual if eqFn is nil.
+func DiffSequence(array interface{}, other interface{}, eqFn func(a, b interface{}) bool) (interface{}, error) {
+	for _, collection := range []interface{}{array, other} {
+		if !IsIterable(collection) {
+			return nil, NotIterableError{collection}
+		}
+	}
+	if eqFn == nil {
+		eqFn = reflect.DeepEqual
+	}
+	olds, news := NewIterable(array).ToArrayOfInterface(), NewIterable(other).ToArrayOfInterface()
+	// lengths[i][j] is the length of the longest common subsequence of olds[i:] and news[j:]
+	lengths := make([][]int, len(olds)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(news)+1)
+	}
+	for i := len(olds) - 1; i >= 0; i-- {
+		for j := len(news) - 1; j >= 0; j-- {
+			switch {
+			case eqFn(olds[i], news[j]):
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	result := []Change{}
+	i, j := 0, 0
+	for i < len(olds) || j < len(news) {
+		switch {
+		case i < len(olds) && j < len(news) && eqFn(olds[i], news[j]):
+			result = append(result, Change{Unchanged, nil, olds[i], news[j], i, j})
+			i++
+			j++
+		case j >= len(news) || (i < len(olds) && lengths[i+1][j] >= lengths[i][j+1]):
+			result = append(result, Change{Removed, nil, olds[i], nil, i, -1})
+			i++
+		default:
+			result = append(result, Change{Added, nil, nil, news[j], -1, j})
+			j++
+		}
+	}
+	return result, nil
+}
+
+// ApplyPatch replays a collection of Change onto array.
+// If keyFn is not nil, changes are matched by key as produced by Diff: removed elements are dropped,
+// changed elements are replaced and added elements are appended. Elements with the same key
+// are matched with the changes of that key in the order of the collection.
+// If keyFn is nil, changes are matched by position as produced by DiffSequence.
+func ApplyPatch(array interface{}, patch interface{}, keyFn func(element interface{}, index int) interface{}) (interface{}, error) {
+	for _, collection := range []interface{}{array, patch} {
+		if !IsIterable(collection) {
+			return nil, NotIterableError{collection}
+		}
+	}
+	elements := NewIterable(array).ToArrayOfInterface()
+	changes := []Change{}
+	iterable := NewIterable(patch)
+	for i := 0; i < iterable.Length(); i++ {
+		change, ok := iterable.At(i).(Change)
+		if !ok {
+			return nil, NotAChangeError{iterable.At(i)}
+		}
+		changes = append(changes, change)
+	}
+	result := []interface{}{}
+	if keyFn == nil {
+		for _, change := range changes {
+			if change.Kind != Added && (change.OldIndex < 0 || change.OldIndex >= len(elements)) {
+				return nil, IndexOutOfBoundsError{change.OldIndex}
+			}
+			switch change.Kind {
+			case Unchanged:
+				result = append(result, elements[change.OldIndex])
+			case Changed, Added:
+				result = append(result, change.New)
+			}
+		}
+		return result, nil
+	}
+	// elements sharing a key are matched with the changes of that key in order
+	byKey := map[interface{}][]Change{}
+	for _, change := range changes {
+		if change.Kind != Added {
+			if !isHashable(change.Key) {
+				return nil, UnhashableIDError{change.Key}
+			}
+			byKey[change.Key] = append(byKey[change.Key], change)
+		}
+	}
+	for i, element := range elements {
+		key := keyFn(element, i)
+		if !isHashable(key) {
+			return nil, UnhashableIDError{key}
+		}
+		pending := byKey[key]
+		if len(pending) == 0 {
+			result = append(result, element)
+			continue
+		}
+		change := pending[0]
+		byKey[key] = pending[1:]
+		switch change.Kind {
+		case Unchanged:
+			result = append(result, element)
+		case Changed:
+			result = append(result, change.New)
+		}
+	}
+	for _, change := range changes {
+		if change.Kind == Added {
+			result = append(result, change.New)
+		}
+	}
+	return result, nil
+}
+
+// keysOf returns the keys of the elements of iterable, an UnhashableIDError if one of them
+// cannot be used as a map key
+func keysOf(iterable IterableInterface, keyFn func(element interface{}, index int) interface{}) ([]interface{}, error) {
+	keys := []interface{}{}
+	for i := 0; i < iterable.Length(); i++ {
+		key := keyFn(iterable.At(i), i)
+		if !isHashable(key) {
+			return nil, UnhashableIDError{key}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func hasKey(indexes map[interface{}]int, key interface{}) bool {
+	_, ok := indexes[key]
+	return ok
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// NotAChangeError discriminates a patch entry that is not a Change
+type NotAChangeError struct {
+	value interface{}
+}
+
+// Error returns a string
+func (notAChangeError NotAChangeError) Error() string {
+	return fmt.Sprintf("%#v is not a Change", notAChangeError.value)
+}
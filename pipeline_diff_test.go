@@ -0,0 +1,117 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func productID(el interface{}, i int) interface{} {
+	return el.(Product).ID
+}
+
+func TestDiff(t *testing.T) {
+	e := expect.New(t)
+	cached := Products{{0, "Iphone 6", 0, 500}, {1, "HTC one", 0, 300}, {2, "Apple Watch", 1, 600}}
+	stored := Products{{0, "Iphone 6", 0, 450}, {2, "Apple Watch", 1, 600}, {3, "ThinkPad", 2, 250}}
+	var changes []pipeline.Change
+	err := pipeline.In(cached).Diff(stored, productID, nil).Out(&changes)
+	e.Expect(err).ToBeNil()
+	kinds := []string{}
+	for _, change := range changes {
+		kinds = append(kinds, fmt.Sprint(change.Key, ":", change.Kind))
+	}
+	e.Expect(fmt.Sprint(kinds)).ToEqual("[0:changed 1:removed 2:unchanged 3:added]")
+	e.Expect(changes[0].New.(Product).Price).ToEqual(450)
+
+	var patched []Product
+	err = pipeline.In(cached).ApplyPatch(changes, productID).Out(&patched)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(patched)).ToEqual(fmt.Sprint(stored))
+}
+
+func TestDiffDuplicateKeys(t *testing.T) {
+	e := expect.New(t)
+	cached := Products{{1, "a", 0, 10}, {1, "b", 0, 20}, {2, "c", 0, 30}}
+	stored := Products{{1, "a", 0, 10}, {2, "c", 0, 35}}
+	var changes []pipeline.Change
+	err := pipeline.In(cached).Diff(stored, productID, nil).Out(&changes)
+	e.Expect(err).ToBeNil()
+	kinds := []string{}
+	for _, change := range changes {
+		kinds = append(kinds, fmt.Sprint(change.Key, ":", change.Kind))
+	}
+	e.Expect(fmt.Sprint(kinds)).ToEqual("[1:unchanged 1:removed 2:changed]")
+
+	var patched []Product
+	err = pipeline.In(cached).ApplyPatch(changes, productID).Out(&patched)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(patched)).ToEqual(fmt.Sprint(stored))
+}
+
+func TestDiffUnhashableKeys(t *testing.T) {
+	e := expect.New(t)
+	byTags := func(element interface{}, index int) interface{} {
+		return []int{element.(int)}
+	}
+	_, err := pipeline.Diff([]int{1}, []int{1}, byTags, nil)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Id []int{1} cannot be used as a map key")
+
+	_, err = pipeline.ApplyPatch([]int{1}, []pipeline.Change{{Kind: pipeline.Removed, Key: 1}}, byTags)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Id []int{1} cannot be used as a map key")
+	_, err = pipeline.ApplyPatch([]int{1}, []pipeline.Change{{Kind: pipeline.Removed, Key: []int{2}}}, func(element interface{}, index int) interface{} {
+		return element
+	})
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Id []int{2} cannot be used as a map key")
+	// changes matched by position have no keys
+	_, err = pipeline.ApplyPatch([]int{1}, []pipeline.Change{{Kind: pipeline.Removed, Key: []int{1}}}, nil)
+	e.Expect(err).ToBeNil()
+}
+
+func TestDiffSequence(t *testing.T) {
+	e := expect.New(t)
+	var changes []pipeline.Change
+	err := pipeline.In("kitten").DiffSequence("sitting", nil).Out(&changes)
+	e.Expect(err).ToBeNil()
+	script := ""
+	for _, change := range changes {
+		switch change.Kind {
+		case pipeline.Unchanged:
+			script += string(change.Old.(rune))
+		case pipeline.Removed:
+			script += "-" + string(change.Old.(rune))
+		case pipeline.Added:
+			script += "+" + string(change.New.(rune))
+		}
+	}
+	e.Expect(script).ToEqual("-k+sitt-e+in+g")
+
+	var patched []rune
+	err = pipeline.In("kitten").ApplyPatch(changes, nil).Out(&patched)
+	e.Expect(err).ToBeNil()
+	e.Expect(string(patched)).ToEqual("sitting")
+
+	err = pipeline.In([]int{1}).ApplyPatch([]int{1}, nil).Out(&patched)
+	e.Expect(err).Not().ToBeNil()
+}
@@ -84,15 +84,38 @@
 //
 //## Implemented pipelines
 //
+//- ApplyPatch
+//- BinarySearch
+//- BottomK
+//- Branch
+//- CheckSorted
 //- Chunk
 //- Compact
 //- Concat
+//- ConnectedComponents
+//- Diff
 //- Difference
+//- DiffSequence
+//- DOT
 //- Equals
 //- Every
+//- Explain
 //- Filter
+//- FilterExpr
+//- FilterWith
 //- First
+//- FlatMap
 //- Flatten
+//- FlattenDeep
+//- FromCSV
+//- FromFS
+//- FromJSONArray
+//- FromLines
+//- FromNDJSON
+//- FromRows
+//- FromScanner
+//- FromWords
+//- Glob
 //- GroupBy
 //- Head
 //- IndexOf
@@ -100,19 +123,48 @@
 //- Last
 //- LastIndexOf
 //- Map
+//- MapE
+//- MapExpr
+//- MapWith
+//- MergeSorted
+//- Nest
+//- NewGraph
+//- NthElement
+//- OnError
+//- OpWith
+//- Optimize
+//- OutWith
+//- ParallelMapE
 //- Push
+//- Query
 //- Reduce
 //- ReduceRight
 //- Reverse
 //- Slice
 //- Some
 //- Sort
+//- SortedDifference
+//- SortedIntersection
+//- SortedUnion
+//- SortedUnique
 //- Splice
 //- Tail
+//- Tee
+//- ToCSV
+//- ToJSON
 //- ToMap
+//- ToNDJSON
+//- TopK
+//- TopKBy
+//- TopoSort
+//- ToWriter
 //- Union
 //- Unique
+//- Unnest
 //- Unshift
+//- Walk
+//- WithObserver
+//- WithSideInput
 //- Without
 //- Xor
 //- Zip
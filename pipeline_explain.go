@@ -0,0 +1,175 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// maxParamLength is the length beyond which the parameters of a step are truncated by Explain
+const maxParamLength = 40
+
+// maxParamItems is the number of items of a slice, array or map parameter listed by Explain
+const maxParamItems = 3
+
+// maxParamDepth is the number of nested slices, arrays and maps listed by Explain
+const maxParamDepth = 2
+
+// Explain returns the plan of the pipeline, one numbered step per line.
+// Step numbers are the ones of StepError.
+//
+//	In([]int)
+//	1. Filter(func)
+//	2. Head(2)
+func (pipeline *Pipeline) Explain() string {
+	lines := []string{fmt.Sprintf("In(%s)", describeSource(pipeline.in))}
+	for i, command := range pipeline.commands {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, command.describe()))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// DOT returns the plan of the pipeline as a Graphviz graph. Pipelines given as parameters
+// of a step, such as the inputs of a query join, are drawn as branches joining that step.
+func (pipeline *Pipeline) DOT() string {
+	builder := &strings.Builder{}
+	builder.WriteString("digraph pipeline {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	pipeline.dot(builder, "")
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// dot writes the nodes and edges of the pipeline with ids starting with prefix,
+// it returns the id of its last node
+func (pipeline *Pipeline) dot(builder *strings.Builder, prefix string) string {
+	last := prefix + "in"
+	fmt.Fprintf(builder, "\t%s [label=%q shape=ellipse];\n", last, "In("+describeSource(pipeline.in)+")")
+	for i, command := range pipeline.commands {
+		id := fmt.Sprintf("%sstep%d", prefix, i+1)
+		fmt.Fprintf(builder, "\t%s [label=%q];\n", id, fmt.Sprintf("%d. %s", i+1, command.describe()))
+		fmt.Fprintf(builder, "\t%s -> %s;\n", last, id)
+		for j, param := range command.params {
+			if branch, ok := param.(*Pipeline); ok {
+				end := branch.dot(builder, fmt.Sprintf("%sstep%d_%d_", prefix, i+1, j+1))
+				fmt.Fprintf(builder, "\t%s -> %s [style=dashed];\n", end, id)
+			}
+		}
+		last = id
+	}
+	return last
+}
+
+// describe returns the name of the command followed by its parameters
+func (command command) describe() string {
+	params := []string{}
+	for _, param := range command.params {
+		params = append(params, describeParam(param))
+	}
+	return fmt.Sprintf("%s(%s)", command.name, strings.Join(params, ", "))
+}
+
+func describeSource(source interface{}) string {
+	if source == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(source).String()
+}
+
+// describeParam formats a parameter of a step: functions by name, strings quoted
+// and long values truncated
+func describeParam(param interface{}) string {
+	return describeValue(param, 0)
+}
+
+// describeValue formats a parameter nested in depth slices, arrays and maps
+func describeValue(param interface{}, depth int) string {
+	if param == nil {
+		return "nil"
+	}
+	var text string
+	switch value := param.(type) {
+	case *Pipeline:
+		return "pipeline"
+	case string:
+		text = fmt.Sprintf("%q", value)
+	case fmt.Stringer:
+		text = value.String()
+	default:
+		v := reflect.ValueOf(param)
+		switch v.Kind() {
+		case reflect.Func:
+			return describeFunc(v)
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				text = fmt.Sprint(param)
+				break
+			}
+			if depth == maxParamDepth {
+				return "[...]"
+			}
+			// items are truncated one by one
+			items := []string{}
+			for i := 0; i < v.Len() && i < maxParamItems; i++ {
+				items = append(items, describeValue(v.Index(i).Interface(), depth+1))
+			}
+			if v.Len() > maxParamItems {
+				items = append(items, fmt.Sprintf("... (%d items)", v.Len()))
+			}
+			return "[" + strings.Join(items, " ") + "]"
+		case reflect.Map:
+			if depth == maxParamDepth {
+				return "map[...]"
+			}
+			items := []string{}
+			for i, key := range sortedKeys(v) {
+				if i == maxParamItems {
+					items = append(items, fmt.Sprintf("... (%d items)", v.Len()))
+					break
+				}
+				items = append(items, describeValue(key.Interface(), depth+1)+":"+describeValue(v.MapIndex(key).Interface(), depth+1))
+			}
+			return "map[" + strings.Join(items, " ") + "]"
+		default:
+			text = fmt.Sprint(param)
+		}
+	}
+	if runes := []rune(text); len(runes) > maxParamLength {
+		text = string(runes[:maxParamLength-3]) + "..."
+	}
+	return text
+}
+
+// describeFunc returns the name of a named function, or "func" for function literals
+func describeFunc(function reflect.Value) string {
+	if function.IsNil() {
+		return "nil"
+	}
+	name := runtime.FuncForPC(function.Pointer()).Name()
+	if index := strings.LastIndex(name, "/"); index >= 0 {
+		name = name[index+1:]
+	}
+	// function literals are named after their enclosing function, such as main.main.func1.2
+	for _, part := range strings.Split(name, ".")[1:] {
+		if strings.HasPrefix(part, "func") && strings.Trim(part[len("func"):], "0123456789") == "" {
+			return "func"
+		}
+	}
+	return name
+}
@@ -0,0 +1,113 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func isOdd(el interface{}, i int) bool {
+	return el.(int)%2 == 1
+}
+
+func TestExplain(t *testing.T) {
+	e := expect.New(t)
+	p := pipeline.In([]int{1, 2, 3}).
+		Filter(isOdd).
+		Map(func(el interface{}, i int) interface{} { return el }).
+		Push(4, "five").
+		FilterExpr("it > 1").
+		Without(strings.Repeat("x", 50)).
+		Head(2)
+	e.Expect(p.Explain()).ToEqual(`In([]int)
+1. Filter(pipeline_test.isOdd)
+2. Map(func)
+3. Push([4 "five"])
+4. FilterExpr("it > 1")
+5. Without(["xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx...])
+6. Head(2)
+`)
+	recursive := []interface{}{1, nil}
+	recursive[1] = recursive
+	large := pipeline.In([]int{}).Concat(make([]int, 1000)).Without(recursive)
+	e.Expect(large.Explain()).ToEqual(`In([]int)
+1. Concat([[0 0 0 ... (1000 items)]])
+2. Without([[1 [...]]])
+`)
+	looping := map[string]interface{}{"b": 2, "a": 1}
+	looping["c"] = looping
+	looping["d"] = 4
+	e.Expect(pipeline.In([]int{}).Push(looping, [5]int{1, 2, 3, 4, 5}).Explain()).ToEqual(`In([]int)
+1. Push([map["a":1 "b":2 "c":map[...] ... (4 items)] [1 2 3 ... (5 items)]])
+`)
+
+	var result []interface{}
+	err := p.Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(strings.HasPrefix(err.Error(), "Error at step 4 ")).ToBeTrue()
+
+	p = pipeline.Query("SELECT Name, COUNT(*) AS n FROM ? JOIN ? s ON s.ItemID = ID WHERE Price > 10 GROUP BY Name ORDER BY n DESC LIMIT 3", items, []Sale{})
+	e.Expect(p.Explain()).ToEqual(`In([]pipeline_test.Item)
+1. From("")
+2. Join(pipeline, "s", s.ItemID = ID)
+3. Where(Price > 10)
+4. GroupByExpr([Name])
+5. Select([Name COUNT(*) AS n])
+6. OrderBy([2 DESC])
+7. Limit(0, 3)
+8. Map(func)
+`)
+}
+
+func TestDOT(t *testing.T) {
+	e := expect.New(t)
+	p := pipeline.Query("SELECT * FROM ? a JOIN ? b ON a = b", []int{1}, []int{1})
+	e.Expect(p.DOT()).ToEqual(`digraph pipeline {
+	rankdir=LR;
+	node [shape=box];
+	in [label="In([]int)" shape=ellipse];
+	step1 [label="1. From(\"a\")"];
+	in -> step1;
+	step2 [label="2. Join(pipeline, \"b\", a = b)"];
+	step1 -> step2;
+	step2_1_in [label="In([]int)" shape=ellipse];
+	step2_1_in -> step2 [style=dashed];
+	step3 [label="3. Select([])"];
+	step2 -> step3;
+	step4 [label="4. Map(func)"];
+	step3 -> step4;
+}
+`)
+}
+
+func ExamplePipeline_Explain() {
+	p := pipeline.In([]int{3, 1, 2}).
+		Filter(isOdd).
+		Sort(func(a, b interface{}) bool { return a.(int) < b.(int) }).
+		Head(0)
+	fmt.Print(p.Explain())
+	// Output:
+	// In([]int)
+	// 1. Filter(pipeline_test.isOdd)
+	// 2. Sort(func)
+	// 3. Head(0)
+}
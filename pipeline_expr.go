@@ -0,0 +1,1072 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expr is a compiled expression evaluated against an element, such as
+//
+//	Quantity > 3 && Status == 'paid'
+//	{name: Name, total: Quantity * UnitPrice}
+//
+// Identifiers are resolved as struct fields or map keys of the element, `it` is the element itself.
+// Expressions support the arithmetic operators + - * / %, comparisons == != < <= > >=
+// (= and <> too), boolean logic && || ! (and, or, not too), `in` lists, field access, indexing,
+// list and map literals and the string functions len, lower, upper, trim, contains,
+// startsWith, endsWith and replace.
+type Expr struct {
+	source string
+	root   exprNode
+}
+
+// Resolver is implemented by elements that resolve the identifiers of an expression themselves
+type Resolver interface {
+	Resolve(name string) (value interface{}, ok bool)
+}
+
+// Compile parses an expression, a ParseError gives the column of the first syntax error
+func Compile(source string) (*Expr, error) {
+	parser, err := newExprParser(source)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parser.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != tokenEOF {
+		return nil, ParseError{token.column, fmt.Sprintf("unexpected %s", token)}
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// Eval evaluates the expression against element
+func (expr *Expr) Eval(element interface{}) (interface{}, error) {
+	return expr.root.eval(element)
+}
+
+// String returns the source of the expression
+func (expr *Expr) String() string {
+	return expr.source
+}
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// FilterExpr keeps the elements for which a boolean expression is true
+func (pipeline *Pipeline) FilterExpr(source string) *Pipeline {
+	expr, err := Compile(source)
+	return pipeline.push("FilterExpr", func() (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+		return filterExpr(pipeline.in, expr)
+	}, source)
+}
+
+// MapExpr replaces each element by the value of an expression
+func (pipeline *Pipeline) MapExpr(source string) *Pipeline {
+	expr, err := Compile(source)
+	return pipeline.push("MapExpr", func() (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+		return mapExpr(pipeline.in, expr)
+	}, source)
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// FilterExpr keeps the elements of array for which the boolean expression source is true
+func FilterExpr(array interface{}, source string) (interface{}, error) {
+	expr, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return filterExpr(array, expr)
+}
+
+// MapExpr replaces each element of array by the value of the expression source
+func MapExpr(array interface{}, source string) (interface{}, error) {
+	expr, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return mapExpr(array, expr)
+}
+
+func filterExpr(array interface{}, expr *Expr) (interface{}, error) {
+	result := []interface{}{}
+	var evalErr error
+	err := each(array, func(element interface{}, index int) bool {
+		var value interface{}
+		if value, evalErr = expr.Eval(element); evalErr != nil {
+			return false
+		}
+		keep, ok := value.(bool)
+		if !ok {
+			evalErr = EvalError{expr.root.position(), fmt.Sprintf("%s is not a boolean expression, got %#v", expr, value)}
+			return false
+		}
+		if keep {
+			result = append(result, element)
+		}
+		return true
+	})
+	if evalErr != nil {
+		return nil, evalErr
+	}
+	return result, err
+}
+
+func mapExpr(array interface{}, expr *Expr) (interface{}, error) {
+	result := []interface{}{}
+	var evalErr error
+	err := each(array, func(element interface{}, index int) bool {
+		var value interface{}
+		if value, evalErr = expr.Eval(element); evalErr != nil {
+			return false
+		}
+		result = append(result, value)
+		return true
+	})
+	if evalErr != nil {
+		return nil, evalErr
+	}
+	return result, err
+}
+
+/*********************************/
+/*             LEXER             */
+/*********************************/
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	value  interface{}
+	column int
+}
+
+// String returns a string
+func (token token) String() string {
+	switch token.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(token.value.(string))
+	}
+	return fmt.Sprintf("%q", token.text)
+}
+
+// is returns true if token is the operator or the keyword text, keywords are case insensitive
+func (token token) is(text string) bool {
+	switch token.kind {
+	case tokenOperator:
+		return token.text == text
+	case tokenIdent:
+		return strings.EqualFold(token.text, text)
+	}
+	return false
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "<>", "&&", "||", "<", ">", "=", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", "{", "}", ",", ":", ".", "?"}
+
+func tokenize(source string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r, column := runes[i], i+1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			isFloat := false
+			if i+1 < len(runes) && runes[i] == '.' && unicode.IsDigit(runes[i+1]) {
+				isFloat = true
+				for i++; i < len(runes) && unicode.IsDigit(runes[i]); i++ {
+				}
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				isFloat = true
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			text := string(runes[start:i])
+			var value interface{}
+			var err error
+			if isFloat {
+				value, err = strconv.ParseFloat(text, 64)
+			} else {
+				value, err = strconv.Atoi(text)
+			}
+			if err != nil {
+				return nil, ParseError{column, fmt.Sprintf("invalid number %s", text)}
+			}
+			tokens = append(tokens, token{tokenNumber, text, value, column})
+		case r == '\'' || r == '"':
+			text := []rune{}
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, ParseError{column, "unterminated string"}
+				}
+				if runes[i] == r {
+					i++
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						text = append(text, '\n')
+					case 't':
+						text = append(text, '\t')
+					default:
+						text = append(text, runes[i])
+					}
+					continue
+				}
+				text = append(text, runes[i])
+			}
+			tokens = append(tokens, token{tokenString, string(runes[column-1 : i]), string(text), column})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), nil, column})
+		default:
+			operator := ""
+			for _, candidate := range exprOperators {
+				if hasRunePrefix(runes[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, ParseError{column, fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{tokenOperator, operator, nil, column})
+			i += utf8.RuneCountInString(operator)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", nil, len(runes) + 1}), nil
+}
+
+// hasRunePrefix returns true if runes start with prefix
+func hasRunePrefix(runes []rune, prefix string) bool {
+	i := 0
+	for _, r := range prefix {
+		if i >= len(runes) || runes[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+/*********************************/
+/*             PARSER            */
+/*********************************/
+
+// exprParser is a recursive descent parser, from the lowest to the highest precedence:
+// or, and, not, comparisons and in, + -, * / %, unary -, field access and indexing
+type exprParser struct {
+	tokens   []token
+	position int
+	// sql enables the aggregate functions of queries, aggregates counts them
+	sql        bool
+	aggregates int
+}
+
+func newExprParser(source string) (*exprParser, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens}, nil
+}
+
+func (parser *exprParser) peek() token {
+	return parser.tokens[parser.position]
+}
+
+func (parser *exprParser) next() token {
+	token := parser.tokens[parser.position]
+	if token.kind != tokenEOF {
+		parser.position++
+	}
+	return token
+}
+
+// accept consumes the next token if it is the operator or keyword text
+func (parser *exprParser) accept(text string) bool {
+	if parser.peek().is(text) {
+		parser.next()
+		return true
+	}
+	return false
+}
+
+func (parser *exprParser) expect(text string) error {
+	if token := parser.peek(); !token.is(text) {
+		return ParseError{token.column, fmt.Sprintf("expected %q, got %s", text, token)}
+	}
+	parser.next()
+	return nil
+}
+
+func (parser *exprParser) parseExpression() (exprNode, error) {
+	return parser.parseOr()
+}
+
+func (parser *exprParser) parseOr() (exprNode, error) {
+	left, err := parser.parseAnd()
+	for err == nil && (parser.peek().is("||") || parser.peek().is("or")) {
+		column := parser.next().column
+		var right exprNode
+		if right, err = parser.parseAnd(); err == nil {
+			left = logicalNode{"||", left, right, column}
+		}
+	}
+	return left, err
+}
+
+func (parser *exprParser) parseAnd() (exprNode, error) {
+	left, err := parser.parseNot()
+	for err == nil && (parser.peek().is("&&") || parser.peek().is("and")) {
+		column := parser.next().column
+		var right exprNode
+		if right, err = parser.parseNot(); err == nil {
+			left = logicalNode{"&&", left, right, column}
+		}
+	}
+	return left, err
+}
+
+func (parser *exprParser) parseNot() (exprNode, error) {
+	if token := parser.peek(); token.is("!") || token.is("not") {
+		parser.next()
+		operand, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{"!", operand, token.column}, nil
+	}
+	return parser.parseComparison()
+}
+
+func (parser *exprParser) parseComparison() (exprNode, error) {
+	left, err := parser.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	token := parser.peek()
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<>", "<", ">", "="} {
+		if token.is(operator) {
+			parser.next()
+			right, err := parser.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			switch operator {
+			case "=":
+				operator = "=="
+			case "<>":
+				operator = "!="
+			}
+			return binaryNode{operator, left, right, token.column}, nil
+		}
+	}
+	negate := false
+	if token.is("not") && parser.tokens[parser.position+1].is("in") {
+		parser.next()
+		negate = true
+	}
+	if parser.accept("in") {
+		var list exprNode
+		var err error
+		if column := parser.peek().column; parser.accept("(") {
+			var items []exprNode
+			items, err = parser.parseList(")")
+			list = listNode{items, column}
+		} else {
+			list, err = parser.parseAdditive()
+		}
+		if err != nil {
+			return nil, err
+		}
+		var node exprNode = inNode{left, list, token.column}
+		if negate {
+			node = unaryNode{"!", node, token.column}
+		}
+		return node, nil
+	}
+	return left, nil
+}
+
+func (parser *exprParser) parseAdditive() (exprNode, error) {
+	left, err := parser.parseMultiplicative()
+	for err == nil && (parser.peek().is("+") || parser.peek().is("-")) {
+		token := parser.next()
+		var right exprNode
+		if right, err = parser.parseMultiplicative(); err == nil {
+			left = binaryNode{token.text, left, right, token.column}
+		}
+	}
+	return left, err
+}
+
+func (parser *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := parser.parseUnary()
+	for err == nil && (parser.peek().is("*") || parser.peek().is("/") || parser.peek().is("%")) {
+		token := parser.next()
+		var right exprNode
+		if right, err = parser.parseUnary(); err == nil {
+			left = binaryNode{token.text, left, right, token.column}
+		}
+	}
+	return left, err
+}
+
+func (parser *exprParser) parseUnary() (exprNode, error) {
+	if token := parser.peek(); token.is("-") {
+		parser.next()
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{"-", operand, token.column}, nil
+	}
+	return parser.parsePostfix()
+}
+
+func (parser *exprParser) parsePostfix() (exprNode, error) {
+	node, err := parser.parsePrimary()
+	for err == nil {
+		token := parser.peek()
+		switch {
+		case token.is("."):
+			parser.next()
+			name := parser.next()
+			if name.kind != tokenIdent {
+				return nil, ParseError{name.column, fmt.Sprintf("expected a field name, got %s", name)}
+			}
+			node = memberNode{node, name.text, name.column}
+		case token.is("["):
+			parser.next()
+			var index exprNode
+			if index, err = parser.parseExpression(); err == nil {
+				err = parser.expect("]")
+			}
+			node = indexNode{node, index, token.column}
+		default:
+			return node, nil
+		}
+	}
+	return nil, err
+}
+
+func (parser *exprParser) parsePrimary() (exprNode, error) {
+	token := parser.next()
+	switch token.kind {
+	case tokenNumber, tokenString:
+		return literalNode{token.value, token.column}, nil
+	case tokenIdent:
+		switch {
+		case token.is("true"):
+			return literalNode{true, token.column}, nil
+		case token.is("false"):
+			return literalNode{false, token.column}, nil
+		case token.is("nil") || token.is("null"):
+			return literalNode{nil, token.column}, nil
+		}
+		if !parser.peek().is("(") {
+			return identNode{token.text, token.column}, nil
+		}
+		parser.next()
+		if parser.sql && isAggregate(token.text) {
+			return parser.parseAggregate(token)
+		}
+		args, err := parser.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+		function, ok := exprFunctions[token.text]
+		if !ok {
+			return nil, ParseError{token.column, fmt.Sprintf("unknown function %s", token.text)}
+		}
+		if len(args) != function.arity {
+			return nil, ParseError{token.column, fmt.Sprintf("%s expects %d arguments, got %d", token.text, function.arity, len(args))}
+		}
+		return callNode{token.text, function.call, args, token.column}, nil
+	case tokenOperator:
+		switch token.text {
+		case "(":
+			node, err := parser.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return node, parser.expect(")")
+		case "[":
+			items, err := parser.parseList("]")
+			return listNode{items, token.column}, err
+		case "{":
+			return parser.parseMap(token.column)
+		}
+	}
+	return nil, ParseError{token.column, fmt.Sprintf("unexpected %s", token)}
+}
+
+// parseList parses comma separated expressions until end
+func (parser *exprParser) parseList(end string) ([]exprNode, error) {
+	items := []exprNode{}
+	if parser.accept(end) {
+		return items, nil
+	}
+	for {
+		item, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if parser.accept(end) {
+			return items, nil
+		}
+		if err := parser.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (parser *exprParser) parseMap(column int) (exprNode, error) {
+	node := mapNode{column: column}
+	if parser.accept("}") {
+		return node, nil
+	}
+	for {
+		key := parser.next()
+		if key.kind != tokenIdent && key.kind != tokenString {
+			return nil, ParseError{key.column, fmt.Sprintf("expected a key, got %s", key)}
+		}
+		if err := parser.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		name := key.text
+		if key.kind == tokenString {
+			name = key.value.(string)
+		}
+		node.keys = append(node.keys, name)
+		node.values = append(node.values, value)
+		if parser.accept("}") {
+			return node, nil
+		}
+		if err := parser.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*********************************/
+/*           EVALUATION          */
+/*********************************/
+
+type exprNode interface {
+	eval(element interface{}) (interface{}, error)
+	// position returns the column of the node in the source
+	position() int
+}
+
+type literalNode struct {
+	value  interface{}
+	column int
+}
+
+func (node literalNode) position() int { return node.column }
+func (node identNode) position() int   { return node.column }
+func (node memberNode) position() int  { return node.column }
+func (node indexNode) position() int   { return node.column }
+func (node unaryNode) position() int   { return node.column }
+func (node logicalNode) position() int { return node.column }
+func (node binaryNode) position() int  { return node.column }
+func (node inNode) position() int      { return node.column }
+func (node listNode) position() int    { return node.column }
+func (node mapNode) position() int     { return node.column }
+func (node callNode) position() int    { return node.column }
+
+func (node literalNode) eval(element interface{}) (interface{}, error) {
+	return node.value, nil
+}
+
+type identNode struct {
+	name   string
+	column int
+}
+
+func (node identNode) eval(element interface{}) (interface{}, error) {
+	if value, ok := resolve(element, node.name); ok {
+		return value, nil
+	}
+	if node.name == "it" {
+		return element, nil
+	}
+	return nil, EvalError{node.column, fmt.Sprintf("unknown identifier %s", node.name)}
+}
+
+type memberNode struct {
+	target exprNode
+	name   string
+	column int
+}
+
+func (node memberNode) eval(element interface{}) (interface{}, error) {
+	target, err := node.target.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := resolve(target, node.name); ok {
+		return value, nil
+	}
+	return nil, EvalError{node.column, fmt.Sprintf("%#v has no field %s", target, node.name)}
+}
+
+type indexNode struct {
+	target exprNode
+	index  exprNode
+	column int
+}
+
+func (node indexNode) eval(element interface{}) (interface{}, error) {
+	target, err := node.target.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	index, err := node.index.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	value := indirect(reflect.ValueOf(target))
+	switch value.Kind() {
+	case reflect.Map:
+		key := reflect.ValueOf(index)
+		if !key.IsValid() || !key.Type().ConvertibleTo(value.Type().Key()) {
+			return nil, EvalError{node.column, fmt.Sprintf("invalid key %#v", index)}
+		}
+		if result := value.MapIndex(key.Convert(value.Type().Key())); result.IsValid() {
+			return result.Interface(), nil
+		}
+		return nil, nil
+	case reflect.Slice, reflect.Array, reflect.String:
+		i := indirect(reflect.ValueOf(index))
+		if !isInt(i) || i.Int() < 0 || int(i.Int()) >= value.Len() {
+			return nil, EvalError{node.column, fmt.Sprintf("invalid index %#v", index)}
+		}
+		return value.Index(int(i.Int())).Interface(), nil
+	}
+	return nil, EvalError{node.column, fmt.Sprintf("cannot index %#v", target)}
+}
+
+type unaryNode struct {
+	operator string
+	operand  exprNode
+	column   int
+}
+
+func (node unaryNode) eval(element interface{}) (interface{}, error) {
+	operand, err := node.operand.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	if node.operator == "!" {
+		if b, ok := operand.(bool); ok {
+			return !b, nil
+		}
+		return nil, EvalError{node.column, fmt.Sprintf("%#v is not a boolean", operand)}
+	}
+	value := indirect(reflect.ValueOf(operand))
+	switch {
+	case isInt(value):
+		return -int(value.Int()), nil
+	case isUint(value):
+		return -int(value.Uint()), nil
+	case isNumber(value):
+		return -value.Float(), nil
+	}
+	return nil, EvalError{node.column, fmt.Sprintf("%#v is not a number", operand)}
+}
+
+type logicalNode struct {
+	operator string
+	left     exprNode
+	right    exprNode
+	column   int
+}
+
+func (node logicalNode) eval(element interface{}) (interface{}, error) {
+	left, err := node.boolean(node.left, element)
+	// short circuit
+	if err != nil || left == (node.operator == "||") {
+		return left, err
+	}
+	return node.boolean(node.right, element)
+}
+
+func (node logicalNode) boolean(operand exprNode, element interface{}) (bool, error) {
+	value, err := operand.eval(element)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, EvalError{node.column, fmt.Sprintf("%#v is not a boolean", value)}
+	}
+	return b, nil
+}
+
+type binaryNode struct {
+	operator string
+	left     exprNode
+	right    exprNode
+	column   int
+}
+
+func (node binaryNode) eval(element interface{}) (interface{}, error) {
+	left, err := node.left.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	right, err := node.right.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	switch node.operator {
+	case "==":
+		return equalValues(left, right), nil
+	case "!=":
+		return !equalValues(left, right), nil
+	case "<", "<=", ">", ">=":
+		order, err := compare(left, right)
+		if err != nil {
+			return nil, EvalError{node.column, err.Error()}
+		}
+		switch node.operator {
+		case "<":
+			return order < 0, nil
+		case "<=":
+			return order <= 0, nil
+		case ">":
+			return order > 0, nil
+		}
+		return order >= 0, nil
+	}
+	result, err := arithmetic(node.operator, left, right)
+	if err != nil {
+		return nil, EvalError{node.column, err.Error()}
+	}
+	return result, nil
+}
+
+type inNode struct {
+	value  exprNode
+	list   exprNode
+	column int
+}
+
+func (node inNode) eval(element interface{}) (interface{}, error) {
+	value, err := node.value.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	list, err := node.list.eval(element)
+	if err != nil {
+		return nil, err
+	}
+	collection := indirect(reflect.ValueOf(list))
+	switch collection.Kind() {
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			return strings.Contains(collection.String(), s), nil
+		}
+	case reflect.Map:
+		for _, key := range collection.MapKeys() {
+			if equalValues(key.Interface(), value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < collection.Len(); i++ {
+			if equalValues(collection.Index(i).Interface(), value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return nil, EvalError{node.column, fmt.Sprintf("cannot look for %#v in %#v", value, list)}
+}
+
+type listNode struct {
+	items  []exprNode
+	column int
+}
+
+func (node listNode) eval(element interface{}) (interface{}, error) {
+	result := []interface{}{}
+	for _, item := range node.items {
+		value, err := item.eval(element)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+type mapNode struct {
+	keys   []string
+	values []exprNode
+	column int
+}
+
+func (node mapNode) eval(element interface{}) (interface{}, error) {
+	result := map[string]interface{}{}
+	for i, key := range node.keys {
+		value, err := node.values[i].eval(element)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+type callNode struct {
+	name   string
+	call   func(args []interface{}) (interface{}, error)
+	args   []exprNode
+	column int
+}
+
+func (node callNode) eval(element interface{}) (interface{}, error) {
+	args := []interface{}{}
+	for _, arg := range node.args {
+		value, err := arg.eval(element)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	result, err := node.call(args)
+	if err != nil {
+		return nil, EvalError{node.column, fmt.Sprintf("%s : %s", node.name, err)}
+	}
+	return result, nil
+}
+
+type exprFunction struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+var exprFunctions = map[string]exprFunction{
+	"len": {1, func(args []interface{}) (interface{}, error) {
+		value := indirect(reflect.ValueOf(args[0]))
+		switch value.Kind() {
+		case reflect.String:
+			return utf8.RuneCountInString(value.String()), nil
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return value.Len(), nil
+		}
+		return nil, fmt.Errorf("%#v has no length", args[0])
+	}},
+	"lower":      stringFunction(1, func(s []string) interface{} { return strings.ToLower(s[0]) }),
+	"upper":      stringFunction(1, func(s []string) interface{} { return strings.ToUpper(s[0]) }),
+	"trim":       stringFunction(1, func(s []string) interface{} { return strings.TrimSpace(s[0]) }),
+	"contains":   stringFunction(2, func(s []string) interface{} { return strings.Contains(s[0], s[1]) }),
+	"startsWith": stringFunction(2, func(s []string) interface{} { return strings.HasPrefix(s[0], s[1]) }),
+	"endsWith":   stringFunction(2, func(s []string) interface{} { return strings.HasSuffix(s[0], s[1]) }),
+	"replace":    stringFunction(3, func(s []string) interface{} { return strings.Replace(s[0], s[1], s[2], -1) }),
+}
+
+// stringFunction returns an exprFunction whose arguments must be strings
+func stringFunction(arity int, function func(args []string) interface{}) exprFunction {
+	return exprFunction{arity, func(args []interface{}) (interface{}, error) {
+		strs := []string{}
+		for _, arg := range args {
+			value := indirect(reflect.ValueOf(arg))
+			if value.Kind() != reflect.String {
+				return nil, fmt.Errorf("%#v is not a string", arg)
+			}
+			strs = append(strs, value.String())
+		}
+		return function(strs), nil
+	}}
+}
+
+/*********************************/
+/*             HELPERS           */
+/*********************************/
+
+// resolve returns the field or the key name of value
+func resolve(value interface{}, name string) (interface{}, bool) {
+	if resolver, ok := value.(Resolver); ok {
+		return resolver.Resolve(name)
+	}
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Struct:
+		if field, ok := v.Type().FieldByName(name); ok && field.PkgPath == "" {
+			return v.FieldByIndex(field.Index).Interface(), true
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String && v.Type().Key().Kind() != reflect.Interface {
+			return nil, false
+		}
+		if result := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); result.IsValid() {
+			return result.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// indirect dereferences pointers and interfaces
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// equalValues compares numbers regardless of their type
+func equalValues(a, b interface{}) bool {
+	if order, err := compare(a, b); err == nil {
+		return order == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// arithmetic computes integer results when both operands are integers, float64 results otherwise
+func arithmetic(operator string, a, b interface{}) (interface{}, error) {
+	va, vb := indirect(reflect.ValueOf(a)), indirect(reflect.ValueOf(b))
+	if operator == "+" && va.Kind() == reflect.String && vb.Kind() == reflect.String {
+		return va.String() + vb.String(), nil
+	}
+	if !isNumber(va) || !isNumber(vb) {
+		return nil, fmt.Errorf("invalid operation %#v %s %#v", a, operator, b)
+	}
+	if (isInt(va) || isUint(va)) && (isInt(vb) || isUint(vb)) {
+		x, y := toInt(va), toInt(vb)
+		switch operator {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		}
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if operator == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	}
+	x, y := toFloat(va), toFloat(vb)
+	switch operator {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	}
+	return nil, fmt.Errorf("invalid operation %#v %s %#v", a, operator, b)
+}
+
+func toInt(value reflect.Value) int {
+	if isUint(value) {
+		return int(value.Uint())
+	}
+	return int(value.Int())
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// ParseError discriminates a syntax error in an expression
+type ParseError struct {
+	column int
+	reason string
+}
+
+// Error returns a string
+func (parseError ParseError) Error() string {
+	return fmt.Sprintf("Parse error at column %d : %s", parseError.column, parseError.reason)
+}
+
+// EvalError discriminates an expression that cannot be evaluated against an element
+type EvalError struct {
+	column int
+	reason string
+}
+
+// Error returns a string
+func (evalError EvalError) Error() string {
+	return fmt.Sprintf("Evaluation error at column %d : %s", evalError.column, evalError.reason)
+}
@@ -0,0 +1,153 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+type Purchase struct {
+	Product   string
+	Quantity  int
+	UnitPrice float64
+	Status    string
+	Tags      []string
+}
+
+var purchases = []Purchase{
+	{"Iphone", 2, 500, "paid", []string{"phone"}},
+	{"Graphic card", 5, 250, "paid", []string{"pc", "gaming"}},
+	{"Flat screen", 4, 600, "pending", nil},
+}
+
+func TestCompile(t *testing.T) {
+	e := expect.New(t)
+	element := map[string]interface{}{"a": 7, "b": 2.5, "name": " Bob ", "items": []int{1, 2, 3}, "nested": map[string]interface{}{"x": "y"}}
+	for source, expected := range map[string]interface{}{
+		"a / 2 + a % 2 * 10":             13,
+		"a * b":                          17.5,
+		"-a + 1":                         -6,
+		"(a + 1) * 2":                    16,
+		"a > 5 && b <= 2.5 || false":     true,
+		"!(a == 7)":                      false,
+		"a = 7 AND NOT b <> 2.5":         true,
+		"a in [1, 7] and 4 not in items": true,
+		"upper(trim(name)) + '!'":        "BOB!",
+		`startsWith(lower(name), " b") && len(items) == 3`: true,
+		"items[1] + len(nested)":                           3,
+		"nested.x == 'y' && 'o' in name":                   true,
+		"replace('a-b-c', '-', \"+\")":                     "a+b+c",
+		"it.a":                                             7,
+	} {
+		expr, err := pipeline.Compile(source)
+		e.Expect(err).ToBeNil()
+		value, err := expr.Eval(element)
+		e.Expect(err).ToBeNil()
+		e.Expect(value).ToEqual(expected)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	e := expect.New(t)
+	for source, message := range map[string]string{
+		"Quantity >":        "Parse error at column 11 : unexpected end of expression",
+		"Quantity > 3 )":    `Parse error at column 14 : unexpected ")"`,
+		"Status == 'paid":   "Parse error at column 11 : unterminated string",
+		"Quantity # 3":      `Parse error at column 10 : unexpected character '#'`,
+		"shout(Product)":    "Parse error at column 1 : unknown function shout",
+		"lower(Product, 1)": "Parse error at column 1 : lower expects 1 arguments, got 2",
+		"{name Product}":    `Parse error at column 7 : expected ":", got "Product"`,
+		"Tags[0":            `Parse error at column 7 : expected "]", got end of expression`,
+	} {
+		_, err := pipeline.Compile(source)
+		e.Expect(err).Not().ToBeNil()
+		e.Expect(err.Error()).ToEqual(message)
+	}
+	for source, message := range map[string]string{
+		"Price > 3":        "Evaluation error at column 1 : unknown identifier Price",
+		"Product > 3":      `Evaluation error at column 9 : Cannot compare "Iphone" with 3 .`,
+		"Quantity && true": "Evaluation error at column 10 : 2 is not a boolean",
+		"Quantity / 0":     "Evaluation error at column 10 : division by zero",
+		"Tags[3]":          "Evaluation error at column 5 : invalid index 3",
+		"upper(Quantity)":  "Evaluation error at column 1 : upper : 2 is not a string",
+	} {
+		expr, err := pipeline.Compile(source)
+		e.Expect(err).ToBeNil()
+		_, err = expr.Eval(purchases[0])
+		e.Expect(err).Not().ToBeNil()
+		e.Expect(err.Error()).ToEqual(message)
+	}
+}
+
+func TestFilterExpr(t *testing.T) {
+	e := expect.New(t)
+	var result []Purchase
+	err := pipeline.In(purchases).FilterExpr("Quantity > 3 && Status == 'paid' || 'phone' in Tags").Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(result)).ToEqual(2)
+	e.Expect(result[1].Product).ToEqual("Graphic card")
+
+	err = pipeline.In(purchases).FilterExpr("Quantity").Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	evalError := pipeline.EvalError{}
+	err = pipeline.In(purchases).FilterExpr("Quantity + 1").Out(&result)
+	e.Expect(errors.As(err, &evalError)).ToBeTrue()
+	e.Expect(evalError.Error()).ToEqual("Evaluation error at column 10 : Quantity + 1 is not a boolean expression, got 3")
+	err = pipeline.In(purchases).FilterExpr("Quantity >").Out(&result)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestMapExpr(t *testing.T) {
+	e := expect.New(t)
+	var result []map[string]interface{}
+	err := pipeline.In(purchases).MapExpr("{name: lower(Product), total: Quantity * UnitPrice}").Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[name:iphone total:1000] map[name:graphic card total:1250] map[name:flat screen total:2400]]")
+
+	var quantities []int
+	err = pipeline.In(purchases).MapExpr("Quantity * 2").Out(&quantities)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(quantities)).ToEqual("[4 10 8]")
+}
+
+func TestSpecExpr(t *testing.T) {
+	e := expect.New(t)
+	spec, err := pipeline.NewRegistry().LoadSpec([]byte(`[{"op":"filter","expr":"Status == 'paid'"},{"op":"map","expr":"Product"}]`))
+	e.Expect(err).ToBeNil()
+	var result []string
+	e.Expect(spec.Run(purchases, &result)).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[Iphone Graphic card]")
+
+	_, err = pipeline.NewRegistry().LoadSpec([]byte(`[{"op":"filter","expr":"Status =="}]`))
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Invalid step 1 (filter) : Parse error at column 10 : unexpected end of expression")
+}
+
+func ExamplePipeline_FilterExpr() {
+	var result []string
+	err := pipeline.In(purchases).
+		FilterExpr("Quantity > 3 && Status == 'paid'").
+		MapExpr("upper(Product)").
+		Out(&result)
+	fmt.Print(result, " ", err)
+	// Output: [GRAPHIC CARD] <nil>
+}
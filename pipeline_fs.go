@@ -0,0 +1,463 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// FileEntry is a file found by FromFS. Files of zip and tar archives are entries too,
+// their Path is the path of the archive followed by their name in the archive.
+type FileEntry struct {
+	Path string
+	Info fs.FileInfo
+	open func() (io.ReadCloser, error)
+}
+
+// Open opens the content of the file, gzip compressed files are decompressed
+func (entry FileEntry) Open() (io.ReadCloser, error) {
+	reader, err := entry.open()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(entry.Path, ".gz") {
+		return reader, nil
+	}
+	return gunzip(reader)
+}
+
+// Lines returns a Pipeline reading the lines of the file lazily.
+// The file is closed once read, or when the pipeline stops reading it early.
+func (entry FileEntry) Lines() *Pipeline {
+	reader, err := entry.Open()
+	if err != nil {
+		return In(&closingIterator{err: err})
+	}
+	return In(&closingIterator{Iterator: FromLines(reader).in.(Iterator), closer: reader})
+}
+
+/*********************************/
+/*            SOURCES            */
+/*********************************/
+
+// FromFS returns a Pipeline listing the files of fsys under root lazily, as FileEntry,
+// in lexical order. Zip and tar archives (.zip, .tar, .tar.gz, .tgz) are expanded into their files,
+// tar archives are read in one pass while their files are listed.
+func FromFS(fsys fs.FS, root string) *Pipeline {
+	return In(&fsIterator{fsys: fsys, pending: []string{root}})
+}
+
+// fsIterator implements Iterator
+type fsIterator struct {
+	fsys    fs.FS
+	pending []string
+	members []FileEntry
+	archive *tarArchive
+	value   FileEntry
+	err     error
+}
+
+// Next finds the next file
+func (iterator *fsIterator) Next() bool {
+	for iterator.err == nil {
+		if len(iterator.members) > 0 {
+			iterator.value, iterator.members = iterator.members[0], iterator.members[1:]
+			return true
+		}
+		if iterator.archive != nil {
+			member, ok, err := iterator.archive.next()
+			if ok {
+				iterator.value = member
+				return true
+			}
+			iterator.err = err
+			if closeErr := iterator.archive.close(); iterator.err == nil {
+				iterator.err = closeErr
+			}
+			iterator.archive = nil
+			continue
+		}
+		if len(iterator.pending) == 0 {
+			return false
+		}
+		name := iterator.pending[0]
+		iterator.pending = iterator.pending[1:]
+		info, err := fs.Stat(iterator.fsys, name)
+		if err != nil {
+			iterator.err = err
+			return false
+		}
+		fsys := iterator.fsys
+		entry := FileEntry{Path: name, Info: info, open: func() (io.ReadCloser, error) {
+			return fsys.Open(name)
+		}}
+		switch {
+		case info.IsDir():
+			children, err := fs.ReadDir(iterator.fsys, name)
+			if err != nil {
+				iterator.err = err
+				return false
+			}
+			paths := []string{}
+			for _, child := range children {
+				paths = append(paths, path.Join(name, child.Name()))
+			}
+			iterator.pending = append(paths, iterator.pending...)
+		case strings.HasSuffix(name, ".zip"):
+			iterator.members, iterator.err = zipMembers(entry)
+		case strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+			iterator.archive, iterator.err = openTarArchive(entry)
+		default:
+			iterator.value = entry
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the current file
+func (iterator *fsIterator) Value() interface{} {
+	return iterator.value
+}
+
+// Err returns the error that stopped the iteration
+func (iterator *fsIterator) Err() error {
+	return iterator.err
+}
+
+// Close closes the tar archive being listed, if any
+func (iterator *fsIterator) Close() error {
+	if iterator.archive == nil {
+		return nil
+	}
+	err := iterator.archive.close()
+	iterator.archive = nil
+	return err
+}
+
+// zipMembers lists the files of a zip archive. Archives are read in place when their file
+// implements io.ReaderAt, as files of os.DirFS do, and into memory otherwise.
+func zipMembers(archive FileEntry) ([]FileEntry, error) {
+	reader, closer, err := openZip(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	members := []FileEntry{}
+	for i, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		index := i
+		members = append(members, FileEntry{Path: path.Join(archive.Path, file.Name), Info: file.FileInfo(), open: func() (io.ReadCloser, error) {
+			reader, closer, err := openZip(archive)
+			if err != nil {
+				return nil, err
+			}
+			member, err := reader.File[index].Open()
+			if err != nil {
+				closer.Close()
+				return nil, err
+			}
+			return readCloser{member, multiCloser{member, closer}}, nil
+		}})
+	}
+	return members, nil
+}
+
+// openZip opens a zip archive, closer closes the file of the archive
+func openZip(archive FileEntry) (*zip.Reader, io.Closer, error) {
+	file, err := archive.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	if readerAt, ok := file.(io.ReaderAt); ok && archive.Info != nil && !strings.HasSuffix(archive.Path, ".gz") {
+		reader, err := zip.NewReader(readerAt, archive.Info.Size())
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return reader, file, nil
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	return reader, multiCloser{}, err
+}
+
+// tarArchive lists the regular files of a tar archive in one pass. A member opened while
+// the archive is on it is read from the archive stream. Members of uncompressed archives
+// opened later are read at their offset, members of compressed ones are found again.
+type tarArchive struct {
+	entry    FileEntry
+	file     io.Closer
+	reader   *tar.Reader
+	counter  *countingReader
+	position int
+	opened   bool
+	closed   bool
+}
+
+func openTarArchive(entry FileEntry) (*tarArchive, error) {
+	file, reader, counter, err := openTar(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &tarArchive{entry: entry, file: file, reader: reader, counter: counter, position: -1}, nil
+}
+
+// next returns the next regular file of the archive
+func (archive *tarArchive) next() (FileEntry, bool, error) {
+	for {
+		header, err := archive.reader.Next()
+		if err == io.EOF {
+			return FileEntry{}, false, nil
+		}
+		if err != nil {
+			return FileEntry{}, false, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		archive.position++
+		archive.opened = false
+		position, offset, size := archive.position, int64(-1), header.Size
+		if archive.counter != nil {
+			offset = archive.counter.count
+		}
+		return FileEntry{Path: path.Join(archive.entry.Path, header.Name), Info: header.FileInfo(), open: func() (io.ReadCloser, error) {
+			return archive.open(position, offset, size)
+		}}, true, nil
+	}
+}
+
+// open opens the member at position, whose content starts at offset if it is known
+func (archive *tarArchive) open(position int, offset int64, size int64) (io.ReadCloser, error) {
+	if !archive.closed && !archive.opened && archive.position == position {
+		archive.opened = true
+		return io.NopCloser(archive.reader), nil
+	}
+	if offset >= 0 {
+		file, err := archive.entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		if readerAt, ok := file.(io.ReaderAt); ok {
+			return readCloser{io.NewSectionReader(readerAt, offset, size), file}, nil
+		}
+		file.Close()
+	}
+	return openTarMember(archive.entry, position)
+}
+
+func (archive *tarArchive) close() error {
+	if archive.closed {
+		return nil
+	}
+	archive.closed = true
+	return archive.file.Close()
+}
+
+// openTar opens a tar archive, counter counts the bytes read from uncompressed archives
+func openTar(archive FileEntry) (io.ReadCloser, *tar.Reader, *countingReader, error) {
+	file, err := archive.Open()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var stream io.Reader = file
+	var counter *countingReader
+	switch {
+	case strings.HasSuffix(archive.Path, ".tgz"):
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, nil, err
+		}
+		stream = gzipReader
+	case !strings.HasSuffix(archive.Path, ".gz"):
+		counter = &countingReader{reader: file}
+		stream = counter
+	}
+	return file, tar.NewReader(stream), counter, nil
+}
+
+// openTarMember reads a tar archive until its regular file at position
+func openTarMember(archive FileEntry, position int) (io.ReadCloser, error) {
+	file, reader, _, err := openTar(archive)
+	if err != nil {
+		return nil, err
+	}
+	for current := 0; ; {
+		header, err := reader.Next()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if current == position {
+			return readCloser{reader, file}, nil
+		}
+		current++
+	}
+}
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// Glob keeps the FileEntry whose path matches pattern, or whose base name matches it
+// if pattern has no '/'. The syntax of pattern is the one of path.Match.
+func (pipeline *Pipeline) Glob(pattern string) *Pipeline {
+	return pipeline.push("Glob", func() (interface{}, error) {
+		return Glob(pipeline.in, pattern)
+	}, pattern)
+}
+
+// Glob keeps the FileEntry of array whose path matches pattern, or whose base name matches it
+// if pattern has no '/'
+func Glob(array interface{}, pattern string) (interface{}, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return Filter(array, func(element interface{}, index int) bool {
+		entry, ok := element.(FileEntry)
+		if !ok {
+			return false
+		}
+		name := entry.Path
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(name)
+		}
+		matched, _ := path.Match(pattern, name)
+		return matched
+	})
+}
+
+/*********************************/
+/*             HELPERS           */
+/*********************************/
+
+// countingReader counts the bytes read from reader
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (counter *countingReader) Read(buffer []byte) (int, error) {
+	n, err := counter.reader.Read(buffer)
+	counter.count += int64(n)
+	return n, err
+}
+
+// readCloser reads from Reader and closes Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// multiCloser closes its closers in order
+type multiCloser []io.Closer
+
+// Close closes the closers and returns the first error
+func (closers multiCloser) Close() error {
+	var err error
+	for _, closer := range closers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// closingIterator closes a reader once its Iterator is exhausted, or when Close is called
+type closingIterator struct {
+	Iterator
+	closer io.Closer
+	err    error
+}
+
+// Next closes the reader at the end of the iteration
+func (iterator *closingIterator) Next() bool {
+	if iterator.Iterator == nil {
+		return false
+	}
+	if iterator.Iterator.Next() {
+		return true
+	}
+	if iterator.closer != nil {
+		iterator.err = iterator.closer.Close()
+		iterator.closer = nil
+	}
+	return false
+}
+
+// Close closes the reader if the iteration stopped early
+func (iterator *closingIterator) Close() error {
+	if iterator.closer == nil {
+		return nil
+	}
+	err := iterator.closer.Close()
+	iterator.closer = nil
+	return err
+}
+
+// Err returns the error that stopped the iteration
+func (iterator *closingIterator) Err() error {
+	if iterator.Iterator != nil && iterator.Iterator.Err() != nil {
+		return iterator.Iterator.Err()
+	}
+	return iterator.err
+}
+
+// gunzipReader closes both the gzip reader and the compressed reader
+type gunzipReader struct {
+	*gzip.Reader
+	compressed io.Closer
+}
+
+// Close closes the readers
+func (reader gunzipReader) Close() error {
+	err := reader.Reader.Close()
+	if closeErr := reader.compressed.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func gunzip(reader io.ReadCloser) (io.ReadCloser, error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return gunzipReader{gzipReader, reader}, nil
+}
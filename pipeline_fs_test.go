@@ -0,0 +1,220 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func gzipped(content string) []byte {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	writer.Write([]byte(content))
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func zipped(name, content string) []byte {
+	buffer := &bytes.Buffer{}
+	writer := zip.NewWriter(buffer)
+	file, _ := writer.Create(name)
+	file.Write([]byte(content))
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func tarred(files ...string) []byte {
+	buffer := &bytes.Buffer{}
+	writer := tar.NewWriter(buffer)
+	for i := 0; i < len(files); i += 2 {
+		writer.WriteHeader(&tar.Header{Name: files[i], Mode: 0600, Size: int64(len(files[i+1])), Typeflag: tar.TypeReg})
+		writer.Write([]byte(files[i+1]))
+	}
+	writer.Close()
+	return buffer.Bytes()
+}
+
+var logsFS = fstest.MapFS{
+	"logs/app.log":            {Data: []byte("INFO start\nERROR disk full\n")},
+	"logs/2015/app.log.gz":    {Data: gzipped("ERROR timeout\nINFO stop\n")},
+	"logs/archive.zip":        {Data: zipped("old.log", "ERROR old\n")},
+	"logs/backup.tgz":         {Data: gzipped(string(tarred("a.log", "ERROR a\n", "b.log", "INFO b\n")))},
+	"logs/readme.txt":         {Data: []byte("ERROR not a log")},
+	"other/ignored.log":       {Data: []byte("ERROR ignored")},
+	"logs/2015/empty/dir.txt": {Data: []byte{}},
+}
+
+func TestFromFS(t *testing.T) {
+	e := expect.New(t)
+	var entries []pipeline.FileEntry
+	err := pipeline.FromFS(logsFS, "logs").Out(&entries)
+	e.Expect(err).ToBeNil()
+	paths := []string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	e.Expect(strings.Join(paths, " ")).
+		ToEqual("logs/2015/app.log.gz logs/2015/empty/dir.txt logs/app.log logs/archive.zip/old.log logs/backup.tgz/a.log logs/backup.tgz/b.log logs/readme.txt")
+
+	var lines []string
+	err = pipeline.FromFS(logsFS, ".").
+		Glob("logs/*").
+		Glob("*.log*").
+		FlatMap(func(el interface{}, i int) interface{} {
+			return el.(pipeline.FileEntry).Lines()
+		}).
+		Filter(func(el interface{}, i int) bool { return strings.HasPrefix(el.(string), "ERROR") }).
+		Out(&lines)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(lines)).ToEqual("[ERROR disk full]")
+
+	err = pipeline.FromFS(logsFS, ".").
+		Glob("*.log*").
+		FlatMap(func(el interface{}, i int) interface{} {
+			return el.(pipeline.FileEntry).Lines()
+		}).
+		Filter(func(el interface{}, i int) bool { return strings.HasPrefix(el.(string), "ERROR") }).
+		Out(&lines)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(lines)).ToEqual("[ERROR timeout ERROR disk full ERROR old ERROR a ERROR ignored]")
+
+	err = pipeline.FromFS(logsFS, "missing").Out(&entries)
+	e.Expect(err).Not().ToBeNil()
+}
+
+// trackingFS counts the files opened and not closed yet
+type trackingFS struct {
+	fstest.MapFS
+	opened map[string]int
+	open   int
+}
+
+func (fsys *trackingFS) Open(name string) (fs.File, error) {
+	file, err := fsys.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fsys.opened[name]++
+	fsys.open++
+	return &trackedFile{file, fsys}, nil
+}
+
+type trackedFile struct {
+	fs.File
+	fsys *trackingFS
+}
+
+func (file *trackedFile) ReadAt(buffer []byte, offset int64) (int, error) {
+	return file.File.(io.ReaderAt).ReadAt(buffer, offset)
+}
+
+func (file *trackedFile) Close() error {
+	file.fsys.open--
+	return file.File.Close()
+}
+
+func readEntry(el interface{}, i int) interface{} {
+	reader, err := el.(pipeline.FileEntry).Open()
+	if err != nil {
+		return err.Error()
+	}
+	defer reader.Close()
+	data, _ := io.ReadAll(reader)
+	return string(data)
+}
+
+func TestFromFSArchives(t *testing.T) {
+	e := expect.New(t)
+	files, expected := []string{}, []string{}
+	for i := 0; i < 20; i++ {
+		files = append(files, fmt.Sprintf("%d.log", i), fmt.Sprintf("line %d\n", i))
+		expected = append(expected, fmt.Sprintf("line %d\n", i))
+	}
+	fsys := &trackingFS{MapFS: fstest.MapFS{
+		"logs.tar": {Data: tarred(files...)},
+		"logs.tgz": {Data: gzipped(string(tarred(files...)))},
+		"logs.zip": {Data: zipped("0.log", "line 0\n")},
+	}, opened: map[string]int{}}
+
+	// members read while they are listed come from a single pass over the archive
+	for _, name := range []string{"logs.tar", "logs.tgz"} {
+		buffer := &bytes.Buffer{}
+		err := pipeline.FromFS(fsys, name).ToWriter(buffer, func(el interface{}, i int) string {
+			return readEntry(el, i).(string)
+		})
+		e.Expect(err).ToBeNil()
+		e.Expect(buffer.String()).ToEqual(strings.Join(expected, "\n") + "\n")
+		e.Expect(fsys.opened[name]).ToEqual(1)
+	}
+
+	// members read later are read at their offset in uncompressed archives
+	var contents []string
+	err := pipeline.FromFS(fsys, "logs.tar").Map(readEntry).Out(&contents)
+	e.Expect(err).ToBeNil()
+	e.Expect(contents).ToEqual(expected)
+	e.Expect(fsys.opened["logs.tar"]).ToEqual(1 + 1 + len(expected))
+	err = pipeline.FromFS(fsys, "logs.tgz").Map(readEntry).Out(&contents)
+	e.Expect(err).ToBeNil()
+	e.Expect(contents).ToEqual(expected)
+	err = pipeline.FromFS(fsys, "logs.zip").Map(readEntry).Out(&contents)
+	e.Expect(err).ToBeNil()
+	e.Expect(contents).ToEqual([]string{"line 0\n"})
+	e.Expect(fsys.open).ToEqual(0)
+}
+
+func TestFromFSStopsEarly(t *testing.T) {
+	e := expect.New(t)
+	fsys := &trackingFS{MapFS: fstest.MapFS{
+		"logs.tar": {Data: tarred("a.log", "ERROR a\n", "b.log", "INFO b\n")},
+		"app.log":  {Data: []byte("INFO start\nERROR disk full\n")},
+	}, opened: map[string]int{}}
+	err := pipeline.FromFS(fsys, "logs.tar").ToWriter(failingWriter{}, nil)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(fsys.open).ToEqual(0)
+
+	var entry pipeline.FileEntry
+	err = pipeline.FromFS(fsys, "app.log").First().Out(&entry)
+	e.Expect(err).ToBeNil()
+	err = entry.Lines().ToWriter(failingWriter{}, nil)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(fsys.open).ToEqual(0)
+}
+
+func TestFlatMap(t *testing.T) {
+	e := expect.New(t)
+	var result []int
+	err := pipeline.In([]int{1, 2, 3}).FlatMap(func(el interface{}, i int) interface{} {
+		if el.(int) == 2 {
+			return el
+		}
+		return []int{el.(int), el.(int) * 10}
+	}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[1 10 2 3 30]")
+}
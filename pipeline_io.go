@@ -0,0 +1,83 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+/*********************************/
+/*            SOURCES            */
+/*********************************/
+
+// FromScanner returns a Pipeline reading the tokens of reader lazily, as strings.
+// split is a bufio.SplitFunc such as bufio.ScanRunes, scanning errors are reported as StepError.
+func FromScanner(reader io.Reader, split bufio.SplitFunc) *Pipeline {
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(split)
+	return In(&scannerIterator{scanner})
+}
+
+// FromLines returns a Pipeline reading the lines of reader lazily, without line endings
+func FromLines(reader io.Reader) *Pipeline {
+	return FromScanner(reader, bufio.ScanLines)
+}
+
+// FromWords returns a Pipeline reading the space separated words of reader lazily
+func FromWords(reader io.Reader) *Pipeline {
+	return FromScanner(reader, bufio.ScanWords)
+}
+
+// scannerIterator implements Iterator
+type scannerIterator struct {
+	scanner *bufio.Scanner
+}
+
+// Next scans the next token
+func (iterator *scannerIterator) Next() bool {
+	return iterator.scanner.Scan()
+}
+
+// Value returns the current token
+func (iterator *scannerIterator) Value() interface{} {
+	return iterator.scanner.Text()
+}
+
+// Err returns the error that stopped the scanner
+func (iterator *scannerIterator) Err() error {
+	return iterator.scanner.Err()
+}
+
+/*********************************/
+/*             SINKS             */
+/*********************************/
+
+// ToWriter executes the pipeline and writes the elements of the result to writer, one per line.
+// formatFn formats an element, fmt.Sprint is used if formatFn is nil.
+func (pipeline *Pipeline) ToWriter(writer io.Writer, formatFn func(element interface{}, index int) string) error {
+	if formatFn == nil {
+		formatFn = func(element interface{}, index int) string {
+			return fmt.Sprint(element)
+		}
+	}
+	return pipeline.sink(func(element interface{}, index int) error {
+		_, err := io.WriteString(writer, formatFn(element, index)+"\n")
+		return err
+	})
+}
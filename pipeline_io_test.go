@@ -0,0 +1,82 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func TestFromLines(t *testing.T) {
+	e := expect.New(t)
+	var result []string
+	err := pipeline.FromLines(strings.NewReader("first\r\nsecond\n\nfourth")).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprintf("%q", result)).ToEqual(`["first" "second" "" "fourth"]`)
+
+	err = pipeline.FromLines(strings.NewReader("short\n" + strings.Repeat("a", bufio.MaxScanTokenSize+1))).
+		Filter(func(el interface{}, i int) bool { return true }).
+		Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(strings.HasPrefix(err.Error(), "Error at step 1")).ToBeTrue()
+}
+
+func TestFromWords(t *testing.T) {
+	e := expect.New(t)
+	var count int
+	err := pipeline.FromWords(strings.NewReader("the quick brown\n fox  jumps")).
+		Reduce(func(r interface{}, el interface{}, i int) interface{} { return r.(int) + 1 }, 0).
+		Out(&count)
+	e.Expect(err).ToBeNil()
+	e.Expect(count).ToEqual(5)
+}
+
+func TestFromScanner(t *testing.T) {
+	e := expect.New(t)
+	var result []string
+	err := pipeline.FromScanner(strings.NewReader("héllo"), bufio.ScanRunes).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(result)).ToEqual(5)
+	e.Expect(result[1]).ToEqual("é")
+}
+
+func TestToWriter(t *testing.T) {
+	e := expect.New(t)
+	buffer := &bytes.Buffer{}
+	err := pipeline.FromLines(strings.NewReader("b\na\n")).ToWriter(buffer, func(el interface{}, i int) string {
+		return fmt.Sprint(i, ":", el)
+	})
+	e.Expect(err).ToBeNil()
+	e.Expect(buffer.String()).ToEqual("0:b\n1:a\n")
+}
+
+func ExamplePipeline_ToWriter() {
+	err := pipeline.FromLines(strings.NewReader("INFO started\nERROR timeout\nINFO done")).
+		Filter(func(el interface{}, i int) bool { return strings.HasPrefix(el.(string), "ERROR") }).
+		ToWriter(os.Stdout, nil)
+	fmt.Print(err)
+	// Output:
+	// ERROR timeout
+	// <nil>
+}
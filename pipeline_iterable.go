@@ -0,0 +1,205 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+// Iterables of the most common slice types read their elements without reflection.
+// ToArrayOfInterface always returns a new array, callers are free to modify it.
+
+// typedIterable returns an iterable reading array without reflection, or nil
+// if array is not one of the supported slice types
+func typedIterable(array interface{}) IterableInterface {
+	switch t := array.(type) {
+	case []interface{}:
+		return interfaceIterable(t)
+	case []int:
+		return intIterable(t)
+	case []int64:
+		return int64Iterable(t)
+	case []float64:
+		return float64Iterable(t)
+	case []string:
+		return stringIterable(t)
+	case []byte:
+		return byteIterable(t)
+	}
+	return nil
+}
+
+// interfaceIterable implements IterableInterface
+type interfaceIterable []interface{}
+
+// Length is the length
+func (iterable interfaceIterable) Length() int { return len(iterable) }
+
+// At is the value at index
+func (iterable interfaceIterable) At(index int) interface{} { return iterable[index] }
+
+// ToArrayOfInterface returns []interface{}
+func (iterable interfaceIterable) ToArrayOfInterface() []interface{} {
+	return append(make([]interface{}, 0, len(iterable)), iterable...)
+}
+
+// intIterable implements IterableInterface
+type intIterable []int
+
+// Length is the length
+func (iterable intIterable) Length() int { return len(iterable) }
+
+// At is the value at index
+func (iterable intIterable) At(index int) interface{} { return iterable[index] }
+
+// ToArrayOfInterface returns []interface{}
+func (iterable intIterable) ToArrayOfInterface() []interface{} {
+	result := make([]interface{}, len(iterable))
+	for i, value := range iterable {
+		result[i] = value
+	}
+	return result
+}
+
+// int64Iterable implements IterableInterface
+type int64Iterable []int64
+
+// Length is the length
+func (iterable int64Iterable) Length() int { return len(iterable) }
+
+// At is the value at index
+func (iterable int64Iterable) At(index int) interface{} { return iterable[index] }
+
+// ToArrayOfInterface returns []interface{}
+func (iterable int64Iterable) ToArrayOfInterface() []interface{} {
+	result := make([]interface{}, len(iterable))
+	for i, value := range iterable {
+		result[i] = value
+	}
+	return result
+}
+
+// float64Iterable implements IterableInterface
+type float64Iterable []float64
+
+// Length is the length
+func (iterable float64Iterable) Length() int { return len(iterable) }
+
+// At is the value at index
+func (iterable float64Iterable) At(index int) interface{} { return iterable[index] }
+
+// ToArrayOfInterface returns []interface{}
+func (iterable float64Iterable) ToArrayOfInterface() []interface{} {
+	result := make([]interface{}, len(iterable))
+	for i, value := range iterable {
+		result[i] = value
+	}
+	return result
+}
+
+// stringIterable implements IterableInterface
+type stringIterable []string
+
+// Length is the length
+func (iterable stringIterable) Length() int { return len(iterable) }
+
+// At is the value at index
+func (iterable stringIterable) At(index int) interface{} { return iterable[index] }
+
+// ToArrayOfInterface returns []interface{}
+func (iterable stringIterable) ToArrayOfInterface() []interface{} {
+	result := make([]interface{}, len(iterable))
+	for i, value := range iterable {
+		result[i] = value
+	}
+	return result
+}
+
+// byteIterable implements IterableInterface
+type byteIterable []byte
+
+// Length is the length
+func (iterable byteIterable) Length() int { return len(iterable) }
+
+// At is the value at index
+func (iterable byteIterable) At(index int) interface{} { return iterable[index] }
+
+// ToArrayOfInterface returns []interface{}
+func (iterable byteIterable) ToArrayOfInterface() []interface{} {
+	result := make([]interface{}, len(iterable))
+	for i, value := range iterable {
+		result[i] = value
+	}
+	return result
+}
+
+// assignSlice sets output, a pointer to a slice of one of the supported types, to the
+// elements of in without reflection. It returns false if the elements of in are not all
+// of the element type of output, in which case output is left untouched.
+func assignSlice(in interface{}, output interface{}) bool {
+	iterable := typedIterable(in)
+	if iterable == nil {
+		return false
+	}
+	switch out := output.(type) {
+	case *[]interface{}:
+		*out = iterable.ToArrayOfInterface()
+		return true
+	case *[]int:
+		result, ok := make([]int, iterable.Length()), true
+		for i := range result {
+			if result[i], ok = iterable.At(i).(int); !ok {
+				return false
+			}
+		}
+		*out = result
+		return true
+	case *[]int64:
+		result, ok := make([]int64, iterable.Length()), true
+		for i := range result {
+			if result[i], ok = iterable.At(i).(int64); !ok {
+				return false
+			}
+		}
+		*out = result
+		return true
+	case *[]float64:
+		result, ok := make([]float64, iterable.Length()), true
+		for i := range result {
+			if result[i], ok = iterable.At(i).(float64); !ok {
+				return false
+			}
+		}
+		*out = result
+		return true
+	case *[]string:
+		result, ok := make([]string, iterable.Length()), true
+		for i := range result {
+			if result[i], ok = iterable.At(i).(string); !ok {
+				return false
+			}
+		}
+		*out = result
+		return true
+	case *[]byte:
+		result, ok := make([]byte, iterable.Length()), true
+		for i := range result {
+			if result[i], ok = iterable.At(i).(byte); !ok {
+				return false
+			}
+		}
+		*out = result
+		return true
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func TestNewIterableTyped(t *testing.T) {
+	e := expect.New(t)
+	for _, array := range []interface{}{
+		[]int{1, 2}, []int64{1, 2}, []float64{1, 2}, []string{"a", "b"}, []byte("ab"), []interface{}{1, "b"},
+	} {
+		iterable := pipeline.NewIterable(array)
+		e.Expect(iterable.Length()).ToEqual(2)
+		elements := iterable.ToArrayOfInterface()
+		e.Expect(elements[1]).ToEqual(iterable.At(1))
+		// the array is a copy
+		elements[0] = nil
+		e.Expect(iterable.At(0)).Not().ToBeNil()
+	}
+}
+
+func TestOutTyped(t *testing.T) {
+	e := expect.New(t)
+	var ints []int
+	err := pipeline.In([]interface{}{1, 2, 3}).Out(&ints)
+	e.Expect(err).ToBeNil()
+	e.Expect(ints).ToEqual([]int{1, 2, 3})
+
+	var elements []interface{}
+	err = pipeline.In([]string{"a", "b"}).Out(&elements)
+	e.Expect(err).ToBeNil()
+	e.Expect(elements).ToEqual([]interface{}{"a", "b"})
+
+	var empty []float64
+	err = pipeline.In([]interface{}{}).Out(&empty)
+	e.Expect(err).ToBeNil()
+	e.Expect(empty).ToEqual([]float64{})
+
+	// elements of another type go through reflection
+	var floats []float64
+	err = pipeline.In([]interface{}{1.5, 2}).Out(&floats)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(floats).ToBeNil()
+
+	var sorted []int
+	err = pipeline.In([]int{3, 1, 2}).Sort(lessInt).Out(&sorted)
+	e.Expect(err).ToBeNil()
+	e.Expect(sorted).ToEqual([]int{1, 2, 3})
+}
@@ -0,0 +1,191 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+/*********************************/
+/*            SOURCES            */
+/*********************************/
+
+// FromJSONArray returns a Pipeline reading the elements of a top-level JSON array lazily.
+// Elements are decoded into values of the type of targetOrNil if it is not nil.
+func FromJSONArray(reader io.Reader, targetOrNil interface{}) *Pipeline {
+	return In(&jsonIterator{decoder: json.NewDecoder(reader), target: targetOrNil, array: true})
+}
+
+// FromNDJSON returns a Pipeline reading newline delimited JSON values lazily.
+// Values are decoded into values of the type of targetOrNil if it is not nil.
+func FromNDJSON(reader io.Reader, targetOrNil interface{}) *Pipeline {
+	return In(&jsonIterator{decoder: json.NewDecoder(reader), target: targetOrNil})
+}
+
+// jsonIterator implements Iterator
+type jsonIterator struct {
+	decoder *json.Decoder
+	target  interface{}
+	array   bool
+	started bool
+	done    bool
+	value   interface{}
+	err     error
+}
+
+// Next decodes the next value
+func (iterator *jsonIterator) Next() bool {
+	if iterator.err != nil || iterator.done {
+		return false
+	}
+	if iterator.array && !iterator.started {
+		iterator.started = true
+		token, err := iterator.decoder.Token()
+		if err != nil {
+			iterator.err = err
+			return false
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			iterator.err = NotAJSONArrayError{token}
+			return false
+		}
+	}
+	if iterator.array && !iterator.decoder.More() {
+		iterator.done = true
+		if _, err := iterator.decoder.Token(); err != nil {
+			iterator.err = err
+		}
+		return false
+	}
+	iterator.value, iterator.err = iterator.decode()
+	if iterator.err == io.EOF && !iterator.array {
+		iterator.err = nil
+		iterator.done = true
+		return false
+	}
+	return iterator.err == nil
+}
+
+// Value returns the current value
+func (iterator *jsonIterator) Value() interface{} {
+	return iterator.value
+}
+
+// Err returns the error that stopped the iteration
+func (iterator *jsonIterator) Err() error {
+	return iterator.err
+}
+
+func (iterator *jsonIterator) decode() (interface{}, error) {
+	if iterator.target == nil {
+		var value interface{}
+		err := iterator.decoder.Decode(&value)
+		return value, err
+	}
+	targetType := reflect.TypeOf(iterator.target)
+	if targetType.Kind() == reflect.Ptr {
+		value := reflect.New(targetType.Elem())
+		err := iterator.decoder.Decode(value.Interface())
+		return value.Interface(), err
+	}
+	value := reflect.New(targetType)
+	err := iterator.decoder.Decode(value.Interface())
+	return value.Elem().Interface(), err
+}
+
+/*********************************/
+/*             SINKS             */
+/*********************************/
+
+// ToJSON executes the pipeline and writes the elements of the result as a JSON array,
+// element by element
+func (pipeline *Pipeline) ToJSON(writer io.Writer) error {
+	if _, err := io.WriteString(writer, "["); err != nil {
+		return err
+	}
+	err := pipeline.sink(func(element interface{}, index int) error {
+		data, err := json.Marshal(jsonValue(element))
+		if err != nil {
+			return err
+		}
+		if index > 0 {
+			if _, err = io.WriteString(writer, ","); err != nil {
+				return err
+			}
+		}
+		_, err = writer.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, "]\n")
+	return err
+}
+
+// ToNDJSON executes the pipeline and writes the elements of the result
+// as newline delimited JSON, element by element
+func (pipeline *Pipeline) ToNDJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	return pipeline.sink(func(element interface{}, index int) error {
+		return encoder.Encode(jsonValue(element))
+	})
+}
+
+// jsonValue converts the maps with non string keys produced by GroupBy or ToMap
+// to maps that encoding/json can marshal
+func jsonValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			return value
+		}
+		result := map[string]interface{}{}
+		for _, key := range v.MapKeys() {
+			result[fmt.Sprint(key.Interface())] = jsonValue(v.MapIndex(key).Interface())
+		}
+		return result
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Interface {
+			return value
+		}
+		result := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			result = append(result, jsonValue(v.Index(i).Interface()))
+		}
+		return result
+	}
+	return value
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// NotAJSONArrayError discriminates a JSON document that does not start with an array
+type NotAJSONArrayError struct {
+	token json.Token
+}
+
+// Error returns a string
+func (notAJSONArrayError NotAJSONArrayError) Error() string {
+	return fmt.Sprintf("Expected a JSON array, got %v", notAJSONArrayError.token)
+}
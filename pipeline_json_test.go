@@ -0,0 +1,104 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+type LogEntry struct {
+	Level   string `json:"level"`
+	Service string `json:"service"`
+	Message string `json:"msg"`
+}
+
+const logs = `{"level":"info","service":"api","msg":"started"}
+{"level":"error","service":"db","msg":"connection lost"}
+
+{"level":"error","service":"api","msg":"timeout"}
+`
+
+func TestFromJSONArray(t *testing.T) {
+	e := expect.New(t)
+	var result []interface{}
+	err := pipeline.FromJSONArray(strings.NewReader(`[1, "two", {"three": 3}]`), nil).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(result)).ToEqual(3)
+	e.Expect(result[2].(map[string]interface{})["three"]).ToEqual(3.0)
+
+	var products []Product
+	err = pipeline.FromJSONArray(strings.NewReader(`[{"ID":1,"Name":"Pen","Price":2},{"ID":2,"Name":"Book","Price":12}]`), Product{}).
+		Filter(func(el interface{}, i int) bool { return el.(Product).Price > 5 }).
+		Out(&products)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(products)).ToEqual(1)
+	e.Expect(products[0].Name).ToEqual("Book")
+
+	err = pipeline.FromJSONArray(strings.NewReader(`{"not":"an array"}`), nil).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	err = pipeline.FromJSONArray(strings.NewReader(`[1, 2`), nil).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestFromNDJSON(t *testing.T) {
+	e := expect.New(t)
+	var errors []*LogEntry
+	err := pipeline.FromNDJSON(strings.NewReader(logs), &LogEntry{}).
+		Filter(func(el interface{}, i int) bool { return el.(*LogEntry).Level == "error" }).
+		Out(&errors)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(errors)).ToEqual(2)
+	e.Expect(errors[1].Message).ToEqual("timeout")
+
+	err = pipeline.FromNDJSON(strings.NewReader("{}\n{oops}\n"), &LogEntry{}).Out(&errors)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestToJSON(t *testing.T) {
+	e := expect.New(t)
+	buffer := &bytes.Buffer{}
+	err := pipeline.FromNDJSON(strings.NewReader(logs), LogEntry{}).
+		GroupBy(func(el interface{}, i int) interface{} { return el.(LogEntry).Service }).
+		ToMap(func(v interface{}, k interface{}) (interface{}, interface{}) {
+			return len(v.([]interface{})), k
+		}).
+		Op(func(in interface{}) (interface{}, error) { return []interface{}{in}, nil }).
+		ToJSON(buffer)
+	e.Expect(err).ToBeNil()
+	e.Expect(buffer.String()).ToEqual(`[{"api":2,"db":1}]` + "\n")
+
+	buffer.Reset()
+	err = pipeline.In([]int{}).ToJSON(buffer)
+	e.Expect(err).ToBeNil()
+	e.Expect(buffer.String()).ToEqual("[]\n")
+}
+
+func ExamplePipeline_ToNDJSON() {
+	err := pipeline.FromJSONArray(strings.NewReader(`[{"level":"info","service":"api","msg":"started"}]`), LogEntry{}).
+		ToNDJSON(os.Stdout)
+	fmt.Print(err)
+	// Output:
+	// {"level":"info","service":"api","msg":"started"}
+	// <nil>
+}
@@ -0,0 +1,250 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// StepEvent describes the execution of a step of a pipeline.
+// InLen and OutLen are the lengths of the collections read and produced by the step,
+// -1 for lazy sources (channels and Iterators) and values that are not collections.
+// OutLen, Duration and Err are only set when the step ends.
+// Steps run together by Optimize are reported once, with their names joined
+// such as "Map+Filter" and the number of the last of them, or of the one that failed.
+type StepEvent struct {
+	// Run numbers the runs of all pipelines, the steps of a run share it
+	Run uint64
+	// Step is the number of the step, starting at 1 as in StepError
+	Step int
+	// Steps is the number of steps of the pipeline
+	Steps    int
+	Name     string
+	InLen    int
+	OutLen   int
+	Duration time.Duration
+	Err      error
+}
+
+// Observer is notified when each step of a pipeline starts and ends
+type Observer interface {
+	OnStepStart(event StepEvent)
+	OnStepEnd(event StepEvent)
+}
+
+// RunObserver is an Observer also notified when a run whose steps it observed ends,
+// after its last step or on error
+type RunObserver interface {
+	Observer
+	OnRunEnd(run uint64, err error)
+}
+
+// runs is the number of the last run
+var runs uint64
+
+// WithObserver notifies observer of the execution of every step of the pipeline
+func (pipeline *Pipeline) WithObserver(observer Observer) *Pipeline {
+	pipeline.observers = append(pipeline.observers, observer)
+	return pipeline
+}
+
+// length returns the length of a collection or -1
+func length(value interface{}) int {
+	if iterable, ok := value.(IterableInterface); ok {
+		return iterable.Length()
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice, reflect.String, reflect.Map:
+		return v.Len()
+	}
+	return -1
+}
+
+/*********************************/
+/*             SLOG              */
+/*********************************/
+
+// slogObserver implements Observer
+type slogObserver struct {
+	logger *slog.Logger
+}
+
+// NewSlogObserver returns an Observer logging the start of each step at debug level
+// and its end at info level, or error level if it failed
+func NewSlogObserver(logger *slog.Logger) Observer {
+	return slogObserver{logger}
+}
+
+// OnStepStart logs the start of a step
+func (observer slogObserver) OnStepStart(event StepEvent) {
+	observer.logger.Debug("pipeline step started", "step", event.Step, "op", event.Name, "in", event.InLen)
+}
+
+// OnStepEnd logs the end of a step
+func (observer slogObserver) OnStepEnd(event StepEvent) {
+	level := slog.LevelInfo
+	attributes := []interface{}{"step", event.Step, "op", event.Name, "in", event.InLen, "out", event.OutLen, "duration", event.Duration}
+	if event.Err != nil {
+		level = slog.LevelError
+		attributes = append(attributes, "error", event.Err)
+	}
+	observer.logger.Log(context.Background(), level, "pipeline step ended", attributes...)
+}
+
+/*********************************/
+/*            SUMMARY            */
+/*********************************/
+
+// Summary is an Observer collecting the end events of the steps, to print them as a table
+type Summary struct {
+	mutex  sync.Mutex
+	events []StepEvent
+}
+
+// NewSummary returns an empty Summary
+func NewSummary() *Summary {
+	return &Summary{}
+}
+
+// OnStepStart does nothing
+func (summary *Summary) OnStepStart(event StepEvent) {}
+
+// OnStepEnd records event
+func (summary *Summary) OnStepEnd(event StepEvent) {
+	summary.mutex.Lock()
+	defer summary.mutex.Unlock()
+	summary.events = append(summary.events, event)
+}
+
+// Events returns the recorded events
+func (summary *Summary) Events() []StepEvent {
+	summary.mutex.Lock()
+	defer summary.mutex.Unlock()
+	return append([]StepEvent{}, summary.events...)
+}
+
+// WriteTo writes the recorded events as an aligned table
+func (summary *Summary) WriteTo(writer io.Writer) (int64, error) {
+	buffer := &strings.Builder{}
+	table := tabwriter.NewWriter(buffer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "STEP\tOP\tIN\tOUT\tDURATION\tERROR")
+	for _, event := range summary.Events() {
+		errText := ""
+		if event.Err != nil {
+			errText = event.Err.Error()
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\t%s\n", event.Step, event.Name, formatLength(event.InLen), formatLength(event.OutLen), event.Duration, errText)
+	}
+	table.Flush()
+	n, err := io.WriteString(writer, buffer.String())
+	return int64(n), err
+}
+
+// String returns the table of the recorded events
+func (summary *Summary) String() string {
+	buffer := &strings.Builder{}
+	summary.WriteTo(buffer)
+	return buffer.String()
+}
+
+func formatLength(length int) string {
+	if length < 0 {
+		return "-"
+	}
+	return fmt.Sprint(length)
+}
+
+/*********************************/
+/*            TRACING            */
+/*********************************/
+
+// Tracer starts spans, it can adapt a tracing library to pipelines.
+// parent is nil for the span of a whole run.
+type Tracer interface {
+	StartSpan(name string, parent Span) Span
+}
+
+// Span is an operation traced by a Tracer
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End(err error)
+}
+
+// tracingObserver implements RunObserver
+type tracingObserver struct {
+	tracer Tracer
+	mutex  sync.Mutex
+	runs   map[uint64]*tracedRun
+}
+
+// tracedRun holds the spans of a run, runs of pipelines sharing the observer
+// may be concurrent or nested
+type tracedRun struct {
+	span Span
+	step Span
+}
+
+// NewTracingObserver returns an Observer opening a span named "pipeline" for each run,
+// with a child span per step
+func NewTracingObserver(tracer Tracer) Observer {
+	return &tracingObserver{tracer: tracer, runs: map[uint64]*tracedRun{}}
+}
+
+// OnStepStart starts the span of the step, and the span of the run for the first step
+func (observer *tracingObserver) OnStepStart(event StepEvent) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	run, ok := observer.runs[event.Run]
+	if !ok {
+		run = &tracedRun{span: observer.tracer.StartSpan("pipeline", nil)}
+		run.span.SetAttribute("steps", event.Steps)
+		observer.runs[event.Run] = run
+	}
+	run.step = observer.tracer.StartSpan(event.Name, run.span)
+	run.step.SetAttribute("step", event.Step)
+	run.step.SetAttribute("in", event.InLen)
+}
+
+// OnStepEnd ends the span of the step
+func (observer *tracingObserver) OnStepEnd(event StepEvent) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	if run, ok := observer.runs[event.Run]; ok && run.step != nil {
+		run.step.SetAttribute("out", event.OutLen)
+		run.step.End(event.Err)
+		run.step = nil
+	}
+}
+
+// OnRunEnd ends the span of the run
+func (observer *tracingObserver) OnRunEnd(id uint64, err error) {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	if run, ok := observer.runs[id]; ok {
+		run.span.End(err)
+		delete(observer.runs, id)
+	}
+}
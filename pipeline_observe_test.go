@@ -0,0 +1,152 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func TestWithObserver(t *testing.T) {
+	e := expect.New(t)
+	summary := pipeline.NewSummary()
+	var result []int
+	err := pipeline.In([]int{5, 1, 4, 2, 3}).WithObserver(summary).
+		Filter(func(el interface{}, i int) bool { return el.(int) > 1 }).
+		Sort(func(a, b interface{}) bool { return a.(int) < b.(int) }).
+		Head(1).
+		Out(&result)
+	e.Expect(err).ToBeNil()
+	events := summary.Events()
+	e.Expect(len(events)).ToEqual(3)
+	for i, expected := range []string{"1 3 Filter 5 4\n", "2 3 Sort 4 4\n", "3 3 Head 4 2\n"} {
+		event := events[i]
+		e.Expect(fmt.Sprintln(event.Step, event.Steps, event.Name, event.InLen, event.OutLen)).ToEqual(expected)
+		e.Expect(event.Err).ToBeNil()
+	}
+	lines := strings.Split(summary.String(), "\n")
+	e.Expect(strings.Fields(lines[0])).ToEqual([]string{"STEP", "OP", "IN", "OUT", "DURATION", "ERROR"})
+	e.Expect(strings.Fields(lines[3])[:4]).ToEqual([]string{"3", "Head", "4", "2"})
+
+	summary = pipeline.NewSummary()
+	source := make(chan int, 1)
+	source <- 1
+	close(source)
+	err = pipeline.In(source).WithObserver(summary).Head(10).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(len(summary.Events())).ToEqual(1)
+	e.Expect(summary.Events()[0].InLen).ToEqual(-1)
+	e.Expect(summary.Events()[0].Err).Not().ToBeNil()
+}
+
+func TestNewSlogObserver(t *testing.T) {
+	e := expect.New(t)
+	buffer := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+		if attr.Key == slog.TimeKey || attr.Key == "duration" {
+			return slog.Attr{}
+		}
+		return attr
+	}}))
+	var result []string
+	err := pipeline.In([]string{"a", "b"}).WithObserver(pipeline.NewSlogObserver(logger)).
+		Map(func(el interface{}, i int) interface{} { return strings.ToUpper(el.(string)) }).
+		Tail(5).
+		Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(buffer.String()).ToEqual("level=INFO msg=\"pipeline step ended\" step=1 op=Map in=2 out=2\n" +
+		"level=ERROR msg=\"pipeline step ended\" step=2 op=Tail in=2 out=-1 error=\"Index out of bounds 5\"\n")
+}
+
+type span struct {
+	name   string
+	parent *span
+	tracer *tracer
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.tracer.log = append(s.tracer.log, fmt.Sprintf("%s.%s=%v", s.name, key, value))
+}
+
+func (s *span) End(err error) {
+	s.tracer.log = append(s.tracer.log, fmt.Sprintf("end %s %v", s.name, err))
+}
+
+type tracer struct {
+	log []string
+}
+
+func (t *tracer) StartSpan(name string, parent pipeline.Span) pipeline.Span {
+	if parent != nil {
+		name = parent.(*span).name + "/" + name
+	}
+	t.log = append(t.log, "start "+name)
+	return &span{name: name, tracer: t}
+}
+
+func TestNewTracingObserver(t *testing.T) {
+	e := expect.New(t)
+	tracer := &tracer{}
+	var result []int
+	p := pipeline.In([]int{1, 2, 3}).WithObserver(pipeline.NewTracingObserver(tracer)).Reverse().Push(0)
+	e.Expect(p.Out(&result)).ToBeNil()
+	e.Expect(strings.Join(tracer.log, "\n")).ToEqual(strings.Join([]string{
+		"start pipeline", "pipeline.steps=2",
+		"start pipeline/Reverse", "pipeline/Reverse.step=1", "pipeline/Reverse.in=3", "pipeline/Reverse.out=3", "end pipeline/Reverse <nil>",
+		"start pipeline/Push", "pipeline/Push.step=2", "pipeline/Push.in=3", "pipeline/Push.out=4", "end pipeline/Push <nil>",
+		"end pipeline <nil>",
+	}, "\n"))
+}
+
+func TestNewTracingObserverNestedRuns(t *testing.T) {
+	e := expect.New(t)
+	tracer := &tracer{}
+	observer := pipeline.NewTracingObserver(tracer)
+	// the parent of a Tee runs during the first step of its branch
+	parent := pipeline.In([]int{1, 2, 3}).WithObserver(observer).Reverse()
+	branch := parent.Tee(1)[0].WithObserver(observer).Tail(5)
+	var result []int
+	e.Expect(branch.Out(&result)).Not().ToBeNil()
+	e.Expect(strings.Join(tracer.log, "\n")).ToEqual(strings.Join([]string{
+		"start pipeline", "pipeline.steps=2",
+		"start pipeline/Tee", "pipeline/Tee.step=1", "pipeline/Tee.in=-1",
+		"start pipeline", "pipeline.steps=1",
+		"start pipeline/Reverse", "pipeline/Reverse.step=1", "pipeline/Reverse.in=3", "pipeline/Reverse.out=3", "end pipeline/Reverse <nil>",
+		"end pipeline <nil>",
+		"pipeline/Tee.out=3", "end pipeline/Tee <nil>",
+		"start pipeline/Tail", "pipeline/Tail.step=2", "pipeline/Tail.in=3", "pipeline/Tail.out=-1", "end pipeline/Tail Index out of bounds 5",
+		"end pipeline Error at step 2 : pipeline.IndexOutOfBoundsError{index:5} ",
+	}, "\n"))
+}
+
+func ExampleSummary() {
+	summary := pipeline.NewSummary()
+	var result []int
+	pipeline.In([]int{3, 1, 2}).WithObserver(summary).
+		Filter(func(el interface{}, i int) bool { return el.(int) != 2 }).
+		Out(&result)
+	for _, event := range summary.Events() {
+		fmt.Println(event.Step, event.Name, event.InLen, event.OutLen)
+	}
+	// Output: 1 Filter 3 2
+}
@@ -0,0 +1,260 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"strings"
+)
+
+// Optimize rewrites the steps of a pipeline before they run:
+//
+//   - consecutive Map and Filter steps run in a single loop
+//   - Head and First read only the elements they need through Maps
+//   - Sort followed by Head keeps the smallest elements in a heap instead of sorting everything
+//   - Filter followed by First stops at the first match
+//   - Unique after Union is dropped
+//
+// Results and errors are the ones of the unoptimised pipeline, errors keep the number
+// of the step that failed. Callbacks are not called for every element and steps run
+// together are reported once to observers, so pipelines run their steps as written
+// unless Optimize is called.
+
+// Optimize lets the pipeline rewrite its steps before running them, for pipelines
+// whose callbacks have no side effects
+func (pipeline *Pipeline) Optimize() *Pipeline {
+	pipeline.optimized = true
+	return pipeline
+}
+
+// plannedStep is a command of the optimised plan, running the steps first to last
+// of the pipeline
+type plannedStep struct {
+	command command
+	first   int
+	last    int
+}
+
+// stepFailure is returned by planned steps running several steps to tell which one failed
+type stepFailure struct {
+	step int
+	err  error
+}
+
+// Error returns a string
+func (failure stepFailure) Error() string {
+	return failure.err.Error()
+}
+
+// plan returns the steps to run
+func (pipeline *Pipeline) plan() []plannedStep {
+	steps := []plannedStep{}
+	for i, command := range pipeline.commands {
+		steps = append(steps, plannedStep{command, i + 1, i + 1})
+	}
+	if !pipeline.optimized {
+		return steps
+	}
+	return pipeline.optimize(steps)
+}
+
+func (pipeline *Pipeline) optimize(steps []plannedStep) []plannedStep {
+	result := []plannedStep{}
+	for i := 0; i < len(steps); {
+		step := steps[i]
+		var previous *plannedStep
+		if len(result) > 0 {
+			previous = &result[len(result)-1]
+		}
+		// Union returns unique elements already
+		if previous != nil && strings.HasPrefix(previous.command.name, "Union") && step.command.name == "Unique" && len(step.command.params) == 0 {
+			previous.command.name, previous.last = "Union+Unique", step.last
+			i++
+			continue
+		}
+		if less, ok := comparatorOf(step.command); ok && i+1 < len(steps) {
+			if end, ok := headOf(steps[i+1].command); ok {
+				result = append(result, pipeline.selectHead(step, steps[i+1], less, end))
+				i += 2
+				continue
+			}
+		}
+		if fused, n := pipeline.fuse(steps[i:]); n > 0 {
+			result = append(result, fused)
+			i += n
+			continue
+		}
+		result = append(result, step)
+		i++
+	}
+	return result
+}
+
+// selectHead replaces Sort then Head with BottomK
+func (pipeline *Pipeline) selectHead(sortStep plannedStep, headStep plannedStep, less func(a, b interface{}) bool, end int) plannedStep {
+	run := func() (interface{}, error) {
+		if !IsIterable(pipeline.in) {
+			return nil, stepFailure{sortStep.first, NotIterableError{pipeline.in}}
+		}
+		elements := NewIterable(pipeline.in).ToArrayOfInterface()
+		if end >= len(elements) || end < 0 {
+			return nil, stepFailure{headStep.first, IndexOutOfBoundsError{end}}
+		}
+		return BottomK(elements, end+1, less)
+	}
+	return plannedStep{command{name: "Sort+Head", run: run}, sortStep.first, headStep.last}
+}
+
+// stage is a Map or a Filter run by a fusion
+type stage struct {
+	step      int
+	mapper    func(interface{}, int) interface{}
+	predicate func(interface{}, int) bool
+}
+
+// fusion runs Map and Filter stages in a single loop, then First or Head if any
+type fusion struct {
+	stages []stage
+	first  bool
+	// head is the number of the Head step, 0 if there is none
+	head int
+	end  int
+}
+
+// fuse returns the fusion of the Map and Filter steps at the start of steps and the
+// number of steps it runs, 0 if there is nothing to fuse
+func (pipeline *Pipeline) fuse(steps []plannedStep) (plannedStep, int) {
+	f := fusion{}
+	names := []string{}
+	maps := true
+	n := 0
+	for ; n < len(steps); n++ {
+		command := steps[n].command
+		if mapper, ok := mapperOf(command); ok {
+			f.stages = append(f.stages, stage{step: steps[n].first, mapper: mapper})
+		} else if predicate, ok := predicateOf(command); ok {
+			f.stages = append(f.stages, stage{step: steps[n].first, predicate: predicate})
+			maps = false
+		} else {
+			break
+		}
+		names = append(names, command.name)
+	}
+	if n == 0 {
+		return plannedStep{}, 0
+	}
+	// Maps keep the length of the collection, so Head fails the same way before them
+	if n < len(steps) {
+		command := steps[n].command
+		if command.name == "First" && len(command.params) == 0 {
+			f.first = true
+		} else if end, ok := headOf(command); ok && maps {
+			f.head, f.end = steps[n].first, end
+		}
+		if f.first || f.head > 0 {
+			names = append(names, command.name)
+			n++
+		}
+	}
+	if n < 2 {
+		return plannedStep{}, 0
+	}
+	run := func() (interface{}, error) {
+		return f.run(pipeline.in)
+	}
+	return plannedStep{command{name: strings.Join(names, "+"), run: run}, steps[0].first, steps[n-1].last}, n
+}
+
+func (f fusion) run(in interface{}) (interface{}, error) {
+	if !IsIterable(in) {
+		return nil, stepFailure{f.stages[0].step, NotIterableError{in}}
+	}
+	iterable := NewIterable(in)
+	count := iterable.Length()
+	if f.head > 0 {
+		if f.end >= count || f.end < 0 {
+			return nil, stepFailure{f.head, IndexOutOfBoundsError{f.end}}
+		}
+		count = f.end + 1
+	}
+	// Map returns nil for an empty collection, Filter and Head an empty array
+	var result []interface{}
+	if f.head > 0 || f.stages[len(f.stages)-1].predicate != nil {
+		result = []interface{}{}
+	}
+	// indexes of the stages are the ones of their own input
+	indexes := make([]int, len(f.stages))
+	for i := 0; i < count; i++ {
+		value, kept := iterable.At(i), true
+		for s, stage := range f.stages {
+			index := indexes[s]
+			indexes[s]++
+			if stage.mapper != nil {
+				value = stage.mapper(value, index)
+			} else if !stage.predicate(value, index) {
+				kept = false
+				break
+			}
+		}
+		if !kept {
+			continue
+		}
+		if f.first {
+			return value, nil
+		}
+		result = append(result, value)
+	}
+	if f.first {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// mapperOf returns the callback of a Map step
+func mapperOf(command command) (func(interface{}, int) interface{}, bool) {
+	if command.name != "Map" || len(command.params) != 1 {
+		return nil, false
+	}
+	mapper, ok := command.params[0].(func(interface{}, int) interface{})
+	return mapper, ok && mapper != nil
+}
+
+// predicateOf returns the predicate of a Filter step
+func predicateOf(command command) (func(interface{}, int) bool, bool) {
+	if command.name != "Filter" || len(command.params) != 1 {
+		return nil, false
+	}
+	predicate, ok := command.params[0].(func(interface{}, int) bool)
+	return predicate, ok && predicate != nil
+}
+
+// comparatorOf returns the compare function of a Sort step
+func comparatorOf(command command) (func(a, b interface{}) bool, bool) {
+	if command.name != "Sort" || len(command.params) != 1 {
+		return nil, false
+	}
+	less, ok := command.params[0].(func(a, b interface{}) bool)
+	return less, ok && less != nil
+}
+
+// headOf returns the end index of a Head step
+func headOf(command command) (int, bool) {
+	if command.name != "Head" || len(command.params) != 1 {
+		return 0, false
+	}
+	end, ok := command.params[0].(int)
+	return end, ok
+}
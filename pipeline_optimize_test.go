@@ -0,0 +1,152 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func TestOptimizer(t *testing.T) {
+	e := expect.New(t)
+	calls := 0
+	isEven := func(el interface{}, i int) bool {
+		calls++
+		return el.(int)%2 == 0
+	}
+	var first interface{}
+	err := pipeline.In([]int{1, 3, 4, 5, 6}).Optimize().Filter(isEven).First().Out(&first)
+	e.Expect(err).ToBeNil()
+	e.Expect(first).ToEqual(4)
+	e.Expect(calls).ToEqual(3)
+
+	calls = 0
+	err = pipeline.In([]int{1, 3, 4, 5, 6}).Filter(isEven).First().Out(&first)
+	e.Expect(err).ToBeNil()
+	e.Expect(first).ToEqual(4)
+	e.Expect(calls).ToEqual(5)
+
+	calls = 0
+	square := func(el interface{}, i int) interface{} {
+		calls++
+		return el.(int) * el.(int)
+	}
+	var result []interface{}
+	err = pipeline.In([]int{1, 2, 3, 4, 5}).Optimize().Map(square).Head(1).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual([]interface{}{1, 4})
+	e.Expect(calls).ToEqual(2)
+
+	// errors keep the number of the step that failed
+	err = pipeline.In([]int{1, 2}).Optimize().Map(square).Filter(isEven).Head(5).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(strings.HasPrefix(err.Error(), "Error at step 3 ")).ToBeTrue()
+}
+
+// randomStep adds a step picked at random to a pipeline, the steps are pure so that
+// an optimised pipeline has the result of the unoptimised one
+func randomStep(random *rand.Rand) (string, func(p *pipeline.Pipeline)) {
+	switch n := random.Intn(5); random.Intn(10) {
+	case 0:
+		return "Map(double)", func(p *pipeline.Pipeline) {
+			p.Map(func(el interface{}, i int) interface{} { return el.(int) * 2 })
+		}
+	case 1:
+		return "Map(plusIndex)", func(p *pipeline.Pipeline) {
+			p.Map(func(el interface{}, i int) interface{} { return el.(int) + i })
+		}
+	case 2:
+		return "Filter(isEven)", func(p *pipeline.Pipeline) {
+			p.Filter(func(el interface{}, i int) bool { return el.(int)%2 == 0 })
+		}
+	case 3:
+		return "Filter(evenIndex)", func(p *pipeline.Pipeline) {
+			p.Filter(func(el interface{}, i int) bool { return i%2 == 0 })
+		}
+	case 4:
+		return fmt.Sprintf("Head(%d)", n-1), func(p *pipeline.Pipeline) { p.Head(n - 1) }
+	case 5:
+		return "First()", func(p *pipeline.Pipeline) { p.First() }
+	case 6:
+		// ties keep their order
+		return "Sort(byRemainder)", func(p *pipeline.Pipeline) {
+			p.Sort(func(a, b interface{}) bool { return a.(int)%3 < b.(int)%3 })
+		}
+	case 7:
+		return "Unique()", func(p *pipeline.Pipeline) { p.Unique() }
+	case 8:
+		return fmt.Sprintf("Union([%d])", n), func(p *pipeline.Pipeline) { p.Union([]interface{}{n}) }
+	default:
+		return "Reverse()", func(p *pipeline.Pipeline) { p.Reverse() }
+	}
+}
+
+// randomSource returns a function returning the same source each time it is called
+func randomSource(random *rand.Rand) (string, func() interface{}) {
+	elements := []int{}
+	for i := random.Intn(8); i > 0; i-- {
+		elements = append(elements, random.Intn(10))
+	}
+	switch random.Intn(4) {
+	case 0:
+		return fmt.Sprintf("chan%v", elements), func() interface{} {
+			channel := make(chan int, len(elements))
+			for _, element := range elements {
+				channel <- element
+			}
+			close(channel)
+			return channel
+		}
+	case 1:
+		return "7", func() interface{} { return 7 }
+	default:
+		return fmt.Sprint(elements), func() interface{} { return append([]int{}, elements...) }
+	}
+}
+
+// out returns the result of p
+func out(p *pipeline.Pipeline) (result interface{}, err error) {
+	err = p.Out(&result)
+	return result, err
+}
+
+// TestOptimizerDifferential compares the results of random pipelines with and without the optimiser
+func TestOptimizerDifferential(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 2000; i++ {
+		description, source := randomSource(random)
+		optimized, unoptimized := pipeline.In(source()).Optimize(), pipeline.In(source())
+		steps := []string{description}
+		for j := random.Intn(6) + 1; j > 0; j-- {
+			name, add := randomStep(random)
+			steps = append(steps, name)
+			add(optimized)
+			add(unoptimized)
+		}
+		expected, expectedErr := out(unoptimized)
+		result, err := out(optimized)
+		if !reflect.DeepEqual(result, expected) || !reflect.DeepEqual(err, expectedErr) {
+			t.Fatalf("%s : got %#v, %v, want %#v, %v", strings.Join(steps, "."), result, err, expected, expectedErr)
+		}
+	}
+}
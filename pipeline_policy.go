@@ -0,0 +1,384 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells time to Retry and Timeout, tests can replace it with WithClock
+type Clock interface {
+	// Sleep waits for duration
+	Sleep(duration time.Duration)
+	// After returns a channel receiving the time once duration has elapsed
+	After(duration time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+func (realClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
+
+// Policy changes how Op, MapE and ParallelMapE call their callback:
+//
+//	p.Op(callService, pipeline.Retry(3, 100*time.Millisecond), pipeline.Timeout(time.Second), pipeline.Fallback(nil))
+//
+// MapE and ParallelMapE apply policies to each element.
+type Policy struct {
+	name  string
+	apply func(settings *policies)
+}
+
+// String returns the policy as written
+func (policy Policy) String() string {
+	return policy.name
+}
+
+// Retry calls the callback up to attempts times until it succeeds, waiting backoff
+// after the first failure and twice as long after each of the next ones
+func Retry(attempts int, backoff time.Duration) Policy {
+	return Policy{fmt.Sprintf("Retry(%d, %s)", attempts, backoff), func(settings *policies) {
+		settings.attempts, settings.backoff = attempts, backoff
+	}}
+}
+
+// Timeout fails an attempt with a TimeoutError when the callback does not return within
+// duration. The callback keeps running in the background, its result is ignored.
+func Timeout(duration time.Duration) Policy {
+	return Policy{fmt.Sprintf("Timeout(%s)", duration), func(settings *policies) {
+		settings.timeout = duration
+	}}
+}
+
+// Fallback replaces the result of a callback failing after every attempt. valueOrFunc is
+// either the result or a func(in interface{}, err error) interface{} returning it from
+// the input of the callback and its last error. Other funcs fail with a PolicyError.
+func Fallback(valueOrFunc interface{}) Policy {
+	name := fmt.Sprintf("Fallback(%s)", describeParam(valueOrFunc))
+	fallback, ok := valueOrFunc.(func(in interface{}, err error) interface{})
+	if !ok && reflect.ValueOf(valueOrFunc).Kind() == reflect.Func {
+		return Policy{name, func(settings *policies) {
+			settings.err = PolicyError{name, fmt.Sprintf("%T is not a func(interface{}, error) interface{}", valueOrFunc)}
+		}}
+	}
+	if !ok {
+		fallback = func(in interface{}, err error) interface{} {
+			return valueOrFunc
+		}
+	}
+	return Policy{name, func(settings *policies) {
+		settings.fallback = fallback
+	}}
+}
+
+// WithClock makes Retry and Timeout use clock instead of the time package
+func WithClock(clock Clock) Policy {
+	return Policy{"WithClock", func(settings *policies) {
+		settings.clock = clock
+	}}
+}
+
+// policies are the settings of a callback given by a list of Policy
+type policies struct {
+	attempts int
+	backoff  time.Duration
+	timeout  time.Duration
+	fallback func(in interface{}, err error) interface{}
+	clock    Clock
+	// err is the error of an invalid policy, returned by every call
+	err error
+}
+
+func newPolicies(list []Policy) *policies {
+	settings := &policies{attempts: 1, clock: realClock{}}
+	for _, policy := range list {
+		policy.apply(settings)
+	}
+	if settings.attempts < 1 {
+		settings.attempts = 1
+	}
+	return settings
+}
+
+// call calls callback with in until it succeeds or the attempts are exhausted and returns
+// its result and the number of attempts
+func (settings *policies) call(in interface{}, callback func(in interface{}) (interface{}, error)) (interface{}, int, error) {
+	if settings.err != nil {
+		return nil, 0, settings.err
+	}
+	var err error
+	backoff := settings.backoff
+	for attempt := 1; ; attempt++ {
+		var result interface{}
+		if result, err = settings.attempt(in, callback); err == nil {
+			return result, attempt, nil
+		}
+		if attempt == settings.attempts {
+			if settings.fallback != nil {
+				return settings.fallback(in, err), attempt, nil
+			}
+			return nil, attempt, err
+		}
+		settings.clock.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// attempt calls callback once, within the timeout if any
+func (settings *policies) attempt(in interface{}, callback func(in interface{}) (interface{}, error)) (interface{}, error) {
+	if settings.timeout <= 0 {
+		return safeCall(in, callback)
+	}
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := safeCall(in, callback)
+		done <- outcome{result, err}
+	}()
+	select {
+	case outcome := <-done:
+		return outcome.result, outcome.err
+	case <-settings.clock.After(settings.timeout):
+		return nil, TimeoutError{settings.timeout}
+	}
+}
+
+// safeCall calls callback, a panic of callback is returned as a PanicError so that
+// callbacks running in goroutines do not crash the process
+func safeCall(in interface{}, callback func(in interface{}) (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			result, err = nil, PanicError{value}
+		}
+	}()
+	return callback(in)
+}
+
+// attemptsFailure is returned by steps with policies to tell how many times
+// the failing callback was called
+type attemptsFailure struct {
+	attempts int
+	err      error
+}
+
+// Error returns a string
+func (failure attemptsFailure) Error() string {
+	return failure.err.Error()
+}
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// MapE is Map with a callback that may fail, the step fails with the first element
+// whose callback fails after the policies are applied. A callback that panics fails
+// with a PanicError.
+func (pipeline *Pipeline) MapE(callback func(element interface{}, index int) (interface{}, error), policies ...Policy) *Pipeline {
+	step := len(pipeline.commands) + 1
+	return pipeline.push("MapE", func() (interface{}, error) {
+		return pipeline.mapElements(step, callback, 1, policies)
+	}, append([]interface{}{callback}, policyParams(policies)...)...)
+}
+
+// ParallelMapE is MapE calling callback from workers goroutines, results keep the order
+// of the elements
+func (pipeline *Pipeline) ParallelMapE(workers int, callback func(element interface{}, index int) (interface{}, error), policies ...Policy) *Pipeline {
+	step := len(pipeline.commands) + 1
+	return pipeline.push("ParallelMapE", func() (interface{}, error) {
+		return pipeline.mapElements(step, callback, workers, policies)
+	}, append([]interface{}{workers, callback}, policyParams(policies)...)...)
+}
+
+// mapElements runs the step numbered step of MapE or ParallelMapE, failing elements
+// are dropped and kept as dead letters unless the pipeline fails on errors
+func (pipeline *Pipeline) mapElements(step int, callback func(element interface{}, index int) (interface{}, error), workers int, policies []Policy) (interface{}, error) {
+	result, letters, err := mapWithPolicies(pipeline.in, callback, workers, newPolicies(policies), pipeline.onError != Fail)
+	if pipeline.onError == Collect {
+		for _, letter := range letters {
+			letter.Step = step
+			pipeline.deadLetters = append(pipeline.deadLetters, letter)
+		}
+	}
+	return result, err
+}
+
+func policyParams(policies []Policy) []interface{} {
+	params := []interface{}{}
+	for _, policy := range policies {
+		params = append(params, policy)
+	}
+	return params
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// MapE replaces each element of array by the result of callback, the error is an
+// ElementError for the first element whose callback fails
+func MapE(array interface{}, callback func(element interface{}, index int) (interface{}, error), policies ...Policy) (interface{}, error) {
+	result, _, err := mapWithPolicies(array, callback, 1, newPolicies(policies), false)
+	if failure, ok := err.(attemptsFailure); ok {
+		err = failure.err
+	}
+	return result, err
+}
+
+// ParallelMapE is MapE calling callback from workers goroutines
+func ParallelMapE(array interface{}, workers int, callback func(element interface{}, index int) (interface{}, error), policies ...Policy) (interface{}, error) {
+	result, _, err := mapWithPolicies(array, callback, workers, newPolicies(policies), false)
+	if failure, ok := err.(attemptsFailure); ok {
+		err = failure.err
+	}
+	return result, err
+}
+
+// mapWithPolicies maps the elements of array from workers goroutines. Once an element
+// fails, the elements not yet started are skipped and the lowest failing index is reported.
+// With keepGoing, failing elements are removed from the result and returned as dead letters.
+func mapWithPolicies(array interface{}, callback func(element interface{}, index int) (interface{}, error), workers int, settings *policies, keepGoing bool) (interface{}, []DeadLetter, error) {
+	if !IsIterable(array) {
+		return nil, nil, NotIterableError{array}
+	}
+	if settings.err != nil {
+		return nil, nil, settings.err
+	}
+	iterable := NewIterable(array)
+	size := iterable.Length()
+	result := make([]interface{}, size)
+	failures := make([]error, size)
+	var failed int32
+	apply := func(index int) {
+		if atomic.LoadInt32(&failed) != 0 {
+			return
+		}
+		element := iterable.At(index)
+		value, attempts, err := settings.call(element, func(in interface{}) (interface{}, error) {
+			return callback(in, index)
+		})
+		if err != nil {
+			failures[index] = attemptsFailure{attempts, ElementError{index, err}}
+			if !keepGoing {
+				atomic.StoreInt32(&failed, 1)
+			}
+			return
+		}
+		result[index] = value
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if workers == 1 {
+		for i := 0; i < size; i++ {
+			apply(i)
+		}
+	} else {
+		indexes := make(chan int)
+		wait := sync.WaitGroup{}
+		for i := 0; i < workers; i++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				for index := range indexes {
+					apply(index)
+				}
+			}()
+		}
+		for i := 0; i < size; i++ {
+			indexes <- i
+		}
+		close(indexes)
+		wait.Wait()
+	}
+	if !keepGoing {
+		for _, err := range failures {
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return result, nil, nil
+	}
+	kept := make([]interface{}, 0, size)
+	letters := []DeadLetter{}
+	for i, err := range failures {
+		if err == nil {
+			kept = append(kept, result[i])
+		} else {
+			letters = append(letters, DeadLetter{Index: i, Value: iterable.At(i), Err: err.(attemptsFailure).err.(ElementError).err})
+		}
+	}
+	return kept, letters, nil
+}
+
+// TimeoutError is returned when a callback does not return within the duration of Timeout
+type TimeoutError struct {
+	timeout time.Duration
+}
+
+// Error returns a string
+func (timeoutError TimeoutError) Error() string {
+	return fmt.Sprintf("Timeout after %s", timeoutError.timeout)
+}
+
+// ElementError is the error of the callback of MapE or ParallelMapE for an element
+type ElementError struct {
+	index int
+	err   error
+}
+
+// Error returns a string
+func (elementError ElementError) Error() string {
+	return fmt.Sprintf("Error at element %d : %s", elementError.index, elementError.err)
+}
+
+// Unwrap returns the error of the callback
+func (elementError ElementError) Unwrap() error {
+	return elementError.err
+}
+
+// PanicError is the error of a callback that panicked
+type PanicError struct {
+	value interface{}
+}
+
+// Error returns a string
+func (panicError PanicError) Error() string {
+	return fmt.Sprintf("panic : %v", panicError.value)
+}
+
+// PolicyError discriminates a Policy that cannot be applied
+type PolicyError struct {
+	policy string
+	reason string
+}
+
+// Error returns a string
+func (policyError PolicyError) Error() string {
+	return fmt.Sprintf("Invalid policy %s : %s", policyError.policy, policyError.reason)
+}
@@ -0,0 +1,214 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+// fakeClock records sleeps without waiting, its timeouts expire at once when expired is true
+type fakeClock struct {
+	mutex   sync.Mutex
+	sleeps  []time.Duration
+	expired bool
+}
+
+func (clock *fakeClock) Sleep(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.sleeps = append(clock.sleeps, duration)
+}
+
+func (clock *fakeClock) After(duration time.Duration) <-chan time.Time {
+	channel := make(chan time.Time, 1)
+	if clock.expired {
+		channel <- time.Time{}
+	}
+	return channel
+}
+
+// flaky fails until it has been called calls times
+func flaky(calls int) func(in interface{}) (interface{}, error) {
+	count := 0
+	return func(in interface{}) (interface{}, error) {
+		count++
+		if count < calls {
+			return nil, fmt.Errorf("call %d failed", count)
+		}
+		return in, nil
+	}
+}
+
+func TestOpRetry(t *testing.T) {
+	e := expect.New(t)
+	clock := &fakeClock{}
+	var result int
+	err := pipeline.In(2).Op(flaky(3), pipeline.Retry(3, time.Second), pipeline.WithClock(clock)).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual(2)
+	e.Expect(fmt.Sprint(clock.sleeps)).ToEqual("[1s 2s]")
+
+	clock = &fakeClock{}
+	err = pipeline.In(2).Op(flaky(5), pipeline.Retry(3, time.Second), pipeline.WithClock(clock)).Out(&result)
+	stepError := pipeline.StepError{}
+	e.Expect(errors.As(err, &stepError)).ToBeTrue()
+	e.Expect(stepError.Attempts()).ToEqual(3)
+	e.Expect(errors.Unwrap(err).Error()).ToEqual("call 3 failed")
+	e.Expect(strings.HasSuffix(err.Error(), "after 3 attempts")).ToBeTrue()
+	e.Expect(len(clock.sleeps)).ToEqual(2)
+}
+
+func TestOpTimeout(t *testing.T) {
+	e := expect.New(t)
+	release := make(chan struct{})
+	defer close(release)
+	blocking := func(in interface{}) (interface{}, error) {
+		<-release
+		return in, nil
+	}
+	var result int
+	err := pipeline.In(1).Op(blocking, pipeline.Timeout(time.Minute), pipeline.WithClock(&fakeClock{expired: true})).Out(&result)
+	timeoutError := pipeline.TimeoutError{}
+	e.Expect(errors.As(err, &timeoutError)).ToBeTrue()
+	e.Expect(timeoutError.Error()).ToEqual("Timeout after 1m0s")
+
+	err = pipeline.In(1).Op(flaky(1), pipeline.Timeout(time.Minute), pipeline.WithClock(&fakeClock{})).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual(1)
+}
+
+func TestOpFallback(t *testing.T) {
+	e := expect.New(t)
+	var result int
+	err := pipeline.In(1).Op(flaky(5), pipeline.Retry(2, 0), pipeline.Fallback(-1), pipeline.WithClock(&fakeClock{})).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual(-1)
+
+	err = pipeline.In(1).Op(flaky(5), pipeline.Fallback(func(in interface{}, err error) interface{} {
+		return in.(int) * 10
+	})).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual(10)
+
+	// a func with another signature is not a fallback value
+	err = pipeline.In(1).Op(flaky(5), pipeline.Fallback(func(err error) int { return 0 })).Out(&result)
+	policyError := pipeline.PolicyError{}
+	e.Expect(errors.As(err, &policyError)).ToBeTrue()
+	e.Expect(policyError.Error()).ToEqual("Invalid policy Fallback(func) : func(error) int is not a func(interface{}, error) interface{}")
+	_, err = pipeline.MapE([]int{1}, func(el interface{}, i int) (interface{}, error) { return el, nil }, pipeline.Fallback(func() {}))
+	e.Expect(errors.As(err, &policyError)).ToBeTrue()
+}
+
+func TestMapE(t *testing.T) {
+	e := expect.New(t)
+	calls := map[int]int{}
+	// odd elements fail on their first attempt
+	callback := func(el interface{}, i int) (interface{}, error) {
+		calls[el.(int)]++
+		if el.(int)%2 == 1 && calls[el.(int)] == 1 {
+			return nil, errors.New("unavailable")
+		}
+		return el.(int) * 2, nil
+	}
+	var result []int
+	err := pipeline.In([]int{1, 2, 3}).MapE(callback, pipeline.Retry(2, time.Second), pipeline.WithClock(&fakeClock{})).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual([]int{2, 4, 6})
+
+	calls = map[int]int{}
+	err = pipeline.In([]int{2, 3, 4}).MapE(callback).Out(&result)
+	elementError := pipeline.ElementError{}
+	e.Expect(errors.As(err, &elementError)).ToBeTrue()
+	e.Expect(elementError.Error()).ToEqual("Error at element 1 : unavailable")
+	e.Expect(calls[4]).ToEqual(0)
+
+	mapped, err := pipeline.MapE([]int{1}, func(el interface{}, i int) (interface{}, error) {
+		return nil, errors.New("unavailable")
+	}, pipeline.Fallback(0))
+	e.Expect(err).ToBeNil()
+	e.Expect(mapped).ToEqual([]interface{}{0})
+}
+
+func TestParallelMapE(t *testing.T) {
+	e := expect.New(t)
+	mutex := sync.Mutex{}
+	calls := map[int]int{}
+	callback := func(el interface{}, i int) (interface{}, error) {
+		mutex.Lock()
+		calls[i]++
+		first := calls[i] == 1
+		mutex.Unlock()
+		if i%3 == 0 && first {
+			return nil, errors.New("unavailable")
+		}
+		return el.(int) + 1, nil
+	}
+	elements := []int{}
+	expected := []int{}
+	for i := 0; i < 100; i++ {
+		elements = append(elements, i)
+		expected = append(expected, i+1)
+	}
+	var result []int
+	err := pipeline.In(elements).ParallelMapE(8, callback, pipeline.Retry(2, time.Second), pipeline.WithClock(&fakeClock{})).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual(expected)
+
+	err = pipeline.In(elements).ParallelMapE(8, func(el interface{}, i int) (interface{}, error) {
+		if i >= 50 {
+			return nil, errors.New("unavailable")
+		}
+		return el, nil
+	}).Out(&result)
+	elementError := pipeline.ElementError{}
+	e.Expect(errors.As(err, &elementError)).ToBeTrue()
+	e.Expect(strings.HasSuffix(elementError.Error(), " : unavailable")).ToBeTrue()
+
+	// a panicking callback fails its element instead of crashing the process
+	_, err = pipeline.ParallelMapE([]int{1, 2, 3}, 2, func(el interface{}, i int) (interface{}, error) {
+		if i == 1 {
+			panic("boom")
+		}
+		return el, nil
+	})
+	panicError := pipeline.PanicError{}
+	e.Expect(errors.As(err, &panicError)).ToBeTrue()
+	e.Expect(err.Error()).ToEqual("Error at element 1 : panic : boom")
+}
+
+func ExamplePipeline_Op() {
+	calls := 0
+	fetch := func(in interface{}) (interface{}, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return in.(int) * 2, nil
+	}
+	var result int
+	err := pipeline.In(21).Op(fetch, pipeline.Retry(3, time.Millisecond), pipeline.WithClock(&fakeClock{})).Out(&result)
+	fmt.Print(result, " ", calls, " ", err)
+	// Output: 42 3 <nil>
+}
@@ -0,0 +1,747 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Statement is a prepared SELECT statement over collections, such as
+//
+//	SELECT Country, COUNT(*), SUM(Price) AS total FROM ? WHERE Price > 10
+//	GROUP BY Country HAVING COUNT(*) > 1 ORDER BY 2 DESC LIMIT 5 OFFSET 10
+//
+//	SELECT c.Name, o.Total FROM ? c JOIN ? o ON o.CustomerID = c.ID
+//
+// Each ? is a collection given to In, in order. Conditions and columns are expressions (see Expr)
+// that may use the aggregate functions COUNT, SUM, AVG, MIN and MAX.
+// Rows are map[string]interface{} keyed by column name, the alias of a column or its source text,
+// except with SELECT * over a single collection which returns its elements.
+type Statement struct {
+	source   string
+	aliases  []string
+	joins    []*Expr
+	where    *Expr
+	groupBy  []*Expr
+	having   *Expr
+	columns  []queryColumn
+	orderBy  []queryOrder
+	limit    int
+	offset   int
+	grouped  bool
+	wildcard bool
+}
+
+type queryColumn struct {
+	name string
+	expr *Expr
+}
+
+// String returns a string
+func (column queryColumn) String() string {
+	if column.expr.String() == column.name {
+		return column.name
+	}
+	return fmt.Sprintf("%s AS %s", column.expr, column.name)
+}
+
+// queryOrder sorts by the column at position if expr is nil
+type queryOrder struct {
+	position int
+	expr     *Expr
+	desc     bool
+}
+
+// String returns a string
+func (order queryOrder) String() string {
+	text := fmt.Sprint(order.position + 1)
+	if order.expr != nil {
+		text = order.expr.String()
+	}
+	if order.desc {
+		text += " DESC"
+	}
+	return text
+}
+
+// Query runs a SELECT statement over inputs, see Statement
+func Query(sql string, inputs ...interface{}) *Pipeline {
+	statement, err := PrepareQuery(sql)
+	if err != nil {
+		return In([]interface{}{}).push("Query", func() (interface{}, error) {
+			return nil, err
+		}, sql)
+	}
+	return statement.In(inputs...)
+}
+
+// PrepareQuery parses a SELECT statement so that it can be run several times.
+// Syntax errors are ParseError and unsupported SQL is a QueryError.
+func PrepareQuery(sql string) (*Statement, error) {
+	parser, err := newExprParser(sql)
+	if err != nil {
+		return nil, err
+	}
+	parser.sql = true
+	return (&queryParser{parser, []rune(sql)}).parseSelect()
+}
+
+// In returns a Pipeline running the statement over inputs. Its steps are named after the
+// clauses they run: From, Join, Where, GroupByExpr, Having, Select, OrderBy and Limit,
+// followed by a Map step returning the selected rows.
+func (statement *Statement) In(inputs ...interface{}) *Pipeline {
+	if len(inputs) != len(statement.aliases) {
+		return In([]interface{}{}).push("Query", func() (interface{}, error) {
+			return nil, QueryError{1, fmt.Sprintf("expected %d inputs, got %d", len(statement.aliases), len(inputs))}
+		}, statement.source)
+	}
+	pipeline := In(inputs[0])
+	pipeline.push("From", func() (interface{}, error) {
+		rows := []interface{}{}
+		err := each(pipeline.in, func(element interface{}, index int) bool {
+			rows = append(rows, queryRow{statement.aliases[:1], []interface{}{element}})
+			return true
+		})
+		return rows, err
+	}, statement.aliases[0])
+	for i, on := range statement.joins {
+		input, on, alias := inputs[i+1], on, statement.aliases[i+1]
+		pipeline.push("Join", func() (interface{}, error) {
+			return queryJoin(pipeline.in, input, alias, on)
+		}, In(input), alias, on)
+	}
+	if statement.where != nil {
+		pipeline.push("Where", func() (interface{}, error) {
+			return filterExpr(pipeline.in, statement.where)
+		}, statement.where)
+	}
+	if statement.grouped {
+		pipeline.push("GroupByExpr", func() (interface{}, error) {
+			return queryGroupBy(pipeline.in, statement.groupBy)
+		}, statement.groupBy)
+	}
+	if statement.having != nil {
+		pipeline.push("Having", func() (interface{}, error) {
+			return filterExpr(pipeline.in, statement.having)
+		}, statement.having)
+	}
+	pipeline.push("Select", func() (interface{}, error) {
+		return statement.project(pipeline.in)
+	}, statement.columns)
+	if len(statement.orderBy) > 0 {
+		pipeline.push("OrderBy", func() (interface{}, error) {
+			return statement.sort(pipeline.in)
+		}, statement.orderBy)
+	}
+	if statement.limit >= 0 || statement.offset > 0 {
+		pipeline.push("Limit", func() (interface{}, error) {
+			limit := statement.limit
+			if limit < 0 {
+				limit = NewIterable(pipeline.in).Length()
+			}
+			return take(pipeline.in, statement.offset, limit)
+		}, statement.offset, statement.limit)
+	}
+	return pipeline.Map(func(element interface{}, index int) interface{} {
+		return element.(queryResult).value
+	})
+}
+
+// String returns the source of the statement
+func (statement *Statement) String() string {
+	return statement.source
+}
+
+// queryResult is a projected row along with the row or the group it comes from,
+// so that ORDER BY can use columns that are not selected
+type queryResult struct {
+	source  interface{}
+	columns []interface{}
+	value   interface{}
+}
+
+func (statement *Statement) project(rows interface{}) (interface{}, error) {
+	results := []interface{}{}
+	iterable := NewIterable(rows)
+	for i := 0; i < iterable.Length(); i++ {
+		row := iterable.At(i)
+		if statement.wildcard {
+			results = append(results, queryResult{row, nil, row.(queryRow).star()})
+			continue
+		}
+		columns := []interface{}{}
+		value := map[string]interface{}{}
+		for _, column := range statement.columns {
+			result, err := column.expr.Eval(row)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, result)
+			value[column.name] = result
+		}
+		results = append(results, queryResult{row, columns, value})
+	}
+	return results, nil
+}
+
+func (statement *Statement) sort(results interface{}) (interface{}, error) {
+	sorted := NewIterable(results).ToArrayOfInterface()
+	keys := make([][]interface{}, len(sorted))
+	for i, element := range sorted {
+		result := element.(queryResult)
+		for _, order := range statement.orderBy {
+			if order.expr == nil {
+				keys[i] = append(keys[i], result.columns[order.position])
+				continue
+			}
+			key, err := order.expr.Eval(result.source)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = append(keys[i], key)
+		}
+	}
+	indexes := make([]int, len(sorted))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var sortErr error
+	sort.SliceStable(indexes, func(a, b int) bool {
+		for k, order := range statement.orderBy {
+			result, err := orderValues(keys[indexes[a]][k], keys[indexes[b]][k])
+			if err != nil && sortErr == nil {
+				sortErr = err
+			}
+			if result != 0 {
+				return (result < 0) != order.desc
+			}
+		}
+		return false
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+	result := []interface{}{}
+	for _, index := range indexes {
+		result = append(result, sorted[index])
+	}
+	return result, nil
+}
+
+// orderValues compares a and b, nil comes first
+func orderValues(a, b interface{}) (int, error) {
+	switch {
+	case a == nil && b == nil:
+		return 0, nil
+	case a == nil:
+		return -1, nil
+	case b == nil:
+		return 1, nil
+	}
+	return compare(a, b)
+}
+
+// queryJoin returns the rows of rows combined with the elements of input for which on is true
+func queryJoin(rows interface{}, input interface{}, alias string, on *Expr) (interface{}, error) {
+	if !IsIterable(input) {
+		return nil, NotIterableError{input}
+	}
+	elements := NewIterable(input).ToArrayOfInterface()
+	result := []interface{}{}
+	iterable := NewIterable(rows)
+	for i := 0; i < iterable.Length(); i++ {
+		row := iterable.At(i).(queryRow)
+		for _, element := range elements {
+			joined := queryRow{append(row.aliases[:len(row.aliases):len(row.aliases)], alias), append(row.values[:len(row.values):len(row.values)], element)}
+			value, err := on.Eval(joined)
+			if err != nil {
+				return nil, err
+			}
+			if matched, ok := value.(bool); !ok {
+				return nil, EvalError{on.root.position(), fmt.Sprintf("%s is not a boolean expression, got %#v", on, value)}
+			} else if matched {
+				result = append(result, joined)
+			}
+		}
+	}
+	return result, nil
+}
+
+// queryGroupBy groups rows by the values of keys, in the order of their first row.
+// Without keys, all the rows are in a single group.
+func queryGroupBy(rows interface{}, keys []*Expr) (interface{}, error) {
+	groups := []interface{}{}
+	indexes := map[string]int{}
+	iterable := NewIterable(rows)
+	if len(keys) == 0 {
+		return []interface{}{&queryGroup{iterable.ToArrayOfInterface()}}, nil
+	}
+	for i := 0; i < iterable.Length(); i++ {
+		values := []interface{}{}
+		for _, key := range keys {
+			value, err := key.Eval(iterable.At(i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		id := groupID(values)
+		if _, ok := indexes[id]; !ok {
+			indexes[id] = len(groups)
+			groups = append(groups, &queryGroup{})
+		}
+		group := groups[indexes[id]].(*queryGroup)
+		group.rows = append(group.rows, iterable.At(i))
+	}
+	return groups, nil
+}
+
+// groupID returns the same id for values equal as numbers, such as int 1, int64 1 and float64 1
+func groupID(values []interface{}) string {
+	normalized := []interface{}{}
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		switch {
+		case isInt(v):
+			value = v.Int()
+		case isUint(v) && v.Uint() <= math.MaxInt64:
+			value = int64(v.Uint())
+		case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+			// integral floats in the range of int64 are ids of integers
+			if f := v.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+				value = int64(f)
+			} else {
+				value = f
+			}
+		}
+		normalized = append(normalized, value)
+	}
+	return fmt.Sprintf("%#v", normalized)
+}
+
+// queryRow is a row of a query, the combination of an element of each input
+type queryRow struct {
+	aliases []string
+	values  []interface{}
+}
+
+// Resolve resolves the alias of an input, or a field of the first input that has it
+func (row queryRow) Resolve(name string) (interface{}, bool) {
+	for i, alias := range row.aliases {
+		if alias != "" && alias == name {
+			return row.values[i], true
+		}
+	}
+	for _, value := range row.values {
+		if result, ok := resolve(value, name); ok {
+			return result, true
+		}
+	}
+	return nil, false
+}
+
+func (row queryRow) star() interface{} {
+	if len(row.values) == 1 {
+		return row.values[0]
+	}
+	result := map[string]interface{}{}
+	for i, alias := range row.aliases {
+		result[alias] = row.values[i]
+	}
+	return result
+}
+
+// queryGroup is a group of rows, identifiers are resolved against its first row
+type queryGroup struct {
+	rows []interface{}
+}
+
+// Resolve resolves name against the first row of the group
+func (group *queryGroup) Resolve(name string) (interface{}, bool) {
+	if len(group.rows) == 0 {
+		return nil, false
+	}
+	return resolve(group.rows[0], name)
+}
+
+/*********************************/
+/*             PARSER            */
+/*********************************/
+
+var unsupportedKeywords = []string{"distinct", "left", "right", "full", "outer", "cross", "natural", "union", "intersect", "except", "with", "into", "window", "over"}
+
+type queryParser struct {
+	*exprParser
+	source []rune
+}
+
+func (parser *queryParser) parseSelect() (*Statement, error) {
+	statement := &Statement{source: string(parser.source), limit: -1}
+	if err := parser.keyword("select"); err != nil {
+		return nil, err
+	}
+	if err := parser.unsupported(); err != nil {
+		return nil, err
+	}
+	if parser.accept("*") {
+		statement.wildcard = true
+	} else {
+		for {
+			column, err := parser.parseColumn()
+			if err != nil {
+				return nil, err
+			}
+			statement.columns = append(statement.columns, column)
+			if !parser.accept(",") {
+				break
+			}
+		}
+	}
+	if err := parser.keyword("from"); err != nil {
+		return nil, err
+	}
+	alias, err := parser.parseSource()
+	if err != nil {
+		return nil, err
+	}
+	statement.aliases = append(statement.aliases, alias)
+	for {
+		if err := parser.unsupported(); err != nil {
+			return nil, err
+		}
+		parser.accept("inner")
+		if !parser.accept("join") {
+			break
+		}
+		alias, err := parser.parseSource()
+		if err != nil {
+			return nil, err
+		}
+		statement.aliases = append(statement.aliases, alias)
+		if err := parser.keyword("on"); err != nil {
+			return nil, err
+		}
+		on, err := parser.parseCondition("ON")
+		if err != nil {
+			return nil, err
+		}
+		statement.joins = append(statement.joins, on)
+	}
+	if parser.accept("where") {
+		if statement.where, err = parser.parseCondition("WHERE"); err != nil {
+			return nil, err
+		}
+	}
+	if parser.accept("group") {
+		if err := parser.keyword("by"); err != nil {
+			return nil, err
+		}
+		for {
+			key, err := parser.parseCondition("GROUP BY")
+			if err != nil {
+				return nil, err
+			}
+			statement.groupBy = append(statement.groupBy, key)
+			if !parser.accept(",") {
+				break
+			}
+		}
+	}
+	if parser.accept("having") {
+		if statement.having, err = parser.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if parser.accept("order") {
+		if err := parser.keyword("by"); err != nil {
+			return nil, err
+		}
+		for {
+			order, err := parser.parseOrder(statement)
+			if err != nil {
+				return nil, err
+			}
+			statement.orderBy = append(statement.orderBy, order)
+			if !parser.accept(",") {
+				break
+			}
+		}
+	}
+	if parser.accept("limit") {
+		if statement.limit, err = parser.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if parser.accept("offset") {
+		if statement.offset, err = parser.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.unsupported(); err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != tokenEOF {
+		return nil, ParseError{token.column, fmt.Sprintf("unexpected %s", token)}
+	}
+	statement.grouped = len(statement.groupBy) > 0 || parser.aggregates > 0
+	if statement.wildcard && statement.grouped {
+		return nil, QueryError{1, "SELECT * cannot be grouped"}
+	}
+	return statement, nil
+}
+
+// keyword consumes a mandatory keyword
+func (parser *queryParser) keyword(keyword string) error {
+	if token := parser.peek(); !token.is(keyword) || token.kind != tokenIdent {
+		return ParseError{token.column, fmt.Sprintf("expected %s, got %s", strings.ToUpper(keyword), token)}
+	}
+	parser.next()
+	return nil
+}
+
+// unsupported reports the SQL keywords that are not supported
+func (parser *queryParser) unsupported() error {
+	token := parser.peek()
+	for _, keyword := range unsupportedKeywords {
+		if token.kind == tokenIdent && token.is(keyword) {
+			return QueryError{token.column, fmt.Sprintf("%s is not supported", strings.ToUpper(keyword))}
+		}
+	}
+	if token.is("(") {
+		return QueryError{token.column, "subqueries are not supported"}
+	}
+	return nil
+}
+
+// parseSource parses ? followed by an optional alias
+func (parser *queryParser) parseSource() (string, error) {
+	if err := parser.unsupported(); err != nil {
+		return "", err
+	}
+	if token := parser.peek(); !token.is("?") {
+		return "", QueryError{token.column, fmt.Sprintf("expected ?, got %s, only ? placeholders are supported in FROM and JOIN", token)}
+	}
+	parser.next()
+	parser.accept("as")
+	if token := parser.peek(); token.kind == tokenIdent && !parser.isClause(token) {
+		parser.next()
+		return token.text, nil
+	}
+	return "", nil
+}
+
+// isClause returns true if token starts a clause rather than being an alias
+func (parser *queryParser) isClause(token token) bool {
+	for _, keyword := range append([]string{"join", "inner", "on", "where", "group", "having", "order", "limit", "offset"}, unsupportedKeywords...) {
+		if token.is(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (parser *queryParser) parseColumn() (queryColumn, error) {
+	start := parser.peek()
+	expr, err := parser.parseExpr()
+	if err != nil {
+		return queryColumn{}, err
+	}
+	name := strings.TrimSpace(string(parser.source[start.column-1 : parser.peek().column-1]))
+	switch node := expr.root.(type) {
+	case identNode:
+		name = node.name
+	case memberNode:
+		name = node.name
+	}
+	if parser.accept("as") {
+		alias := parser.next()
+		if alias.kind != tokenIdent && alias.kind != tokenString {
+			return queryColumn{}, ParseError{alias.column, fmt.Sprintf("expected an alias, got %s", alias)}
+		}
+		name = alias.text
+		if alias.kind == tokenString {
+			name = alias.value.(string)
+		}
+	}
+	return queryColumn{name, expr}, nil
+}
+
+// parseCondition parses an expression that cannot use aggregates
+func (parser *queryParser) parseCondition(clause string) (*Expr, error) {
+	start, aggregates := parser.peek(), parser.aggregates
+	expr, err := parser.parseExpr()
+	if err == nil && parser.aggregates != aggregates {
+		return nil, QueryError{start.column, fmt.Sprintf("aggregates are not allowed in %s", clause)}
+	}
+	return expr, err
+}
+
+// parseOrder parses a column position, a column name or an expression followed by ASC or DESC
+func (parser *queryParser) parseOrder(statement *Statement) (queryOrder, error) {
+	start := parser.peek()
+	expr, err := parser.parseExpr()
+	if err != nil {
+		return queryOrder{}, err
+	}
+	order := queryOrder{position: -1, expr: expr}
+	switch node := expr.root.(type) {
+	case literalNode:
+		position, ok := node.value.(int)
+		if !ok || position < 1 || position > len(statement.columns) {
+			return queryOrder{}, QueryError{start.column, fmt.Sprintf("invalid column position %v", node.value)}
+		}
+		order.position, order.expr = position-1, nil
+	case identNode:
+		for i, column := range statement.columns {
+			if column.name == node.name {
+				order.position, order.expr = i, nil
+				break
+			}
+		}
+	}
+	if parser.accept("desc") {
+		order.desc = true
+	} else {
+		parser.accept("asc")
+	}
+	return order, nil
+}
+
+func (parser *queryParser) parseCount() (int, error) {
+	token := parser.next()
+	count, ok := token.value.(int)
+	if token.kind != tokenNumber || !ok {
+		return 0, ParseError{token.column, fmt.Sprintf("expected an integer, got %s", token)}
+	}
+	return count, nil
+}
+
+func (parser *queryParser) parseExpr() (*Expr, error) {
+	start := parser.peek()
+	root, err := parser.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	source := strings.TrimSpace(string(parser.source[start.column-1 : parser.peek().column-1]))
+	return &Expr{source: source, root: root}, nil
+}
+
+/*********************************/
+/*           AGGREGATES          */
+/*********************************/
+
+var aggregateFunctions = []string{"count", "sum", "avg", "min", "max"}
+
+func isAggregate(name string) bool {
+	for _, function := range aggregateFunctions {
+		if strings.EqualFold(function, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAggregate parses the argument of an aggregate function, * for COUNT
+func (parser *exprParser) parseAggregate(function token) (exprNode, error) {
+	node := aggregateNode{name: strings.ToLower(function.text), column: function.column}
+	if node.name != "count" || !parser.accept("*") {
+		arg, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		node.arg = arg
+	}
+	if err := parser.expect(")"); err != nil {
+		return nil, err
+	}
+	parser.aggregates++
+	return node, nil
+}
+
+// aggregateNode evaluates an aggregate function against a group of rows,
+// nil values are ignored
+type aggregateNode struct {
+	name   string
+	arg    exprNode
+	column int
+}
+
+func (node aggregateNode) position() int { return node.column }
+
+func (node aggregateNode) eval(element interface{}) (interface{}, error) {
+	group, ok := element.(*queryGroup)
+	if !ok {
+		return nil, EvalError{node.column, fmt.Sprintf("%s is not allowed outside of a group", strings.ToUpper(node.name))}
+	}
+	if node.arg == nil {
+		return len(group.rows), nil
+	}
+	var result interface{}
+	count := 0
+	for _, row := range group.rows {
+		value, err := node.arg.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		count++
+		switch {
+		case result == nil && node.name != "count":
+			result = value
+		case node.name == "sum" || node.name == "avg":
+			if result, err = arithmetic("+", result, value); err != nil {
+				return nil, EvalError{node.column, err.Error()}
+			}
+		case node.name == "min" || node.name == "max":
+			order, err := compare(value, result)
+			if err != nil {
+				return nil, EvalError{node.column, err.Error()}
+			}
+			if (order < 0) == (node.name == "min") && order != 0 {
+				result = value
+			}
+		}
+	}
+	switch {
+	case node.name == "count":
+		return count, nil
+	case node.name == "avg" && count > 0:
+		return arithmetic("/", toFloat(indirect(valueOf(result))), count)
+	}
+	return result, nil
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// QueryError discriminates a query using unsupported SQL
+type QueryError struct {
+	column int
+	reason string
+}
+
+// Error returns a string
+func (queryError QueryError) Error() string {
+	return fmt.Sprintf("Query error at column %d : %s", queryError.column, queryError.reason)
+}
@@ -0,0 +1,130 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+type Item struct {
+	ID      int
+	Name    string
+	Country string
+	Price   float64
+}
+
+type Sale struct {
+	ItemID   int
+	Quantity int
+}
+
+var items = []Item{
+	{1, "Tea", "China", 12},
+	{2, "Coffee", "Brazil", 25},
+	{3, "Sugar", "Brazil", 5},
+	{4, "Mate", "Argentina", 15},
+	{5, "Cocoa", "Brazil", 30},
+	{6, "Rice", "China", 8},
+}
+
+func TestQuery(t *testing.T) {
+	e := expect.New(t)
+	var result []map[string]interface{}
+	err := pipeline.Query("SELECT Country, COUNT(*), SUM(Price) AS total FROM ? WHERE Price > 10 GROUP BY Country ORDER BY 2 DESC, Country LIMIT 5", items).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[COUNT(*):2 Country:Brazil total:55] map[COUNT(*):1 Country:Argentina total:15] map[COUNT(*):1 Country:China total:12]]")
+
+	err = pipeline.Query("select Name, Price * 2 from ? where Country in ('China', 'Argentina') order by Price desc limit 2 offset 1", items).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[Name:Tea Price * 2:24] map[Name:Rice Price * 2:16]]")
+
+	err = pipeline.Query("SELECT COUNT(*) AS n, AVG(Price) AS average, MIN(Name) AS first, MAX(Price) AS max FROM ?", items).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[average:15.833333333333334 first:Cocoa max:30 n:6]]")
+
+	err = pipeline.Query("SELECT Country FROM ? GROUP BY Country HAVING SUM(Price) >= 20 ORDER BY Country", items).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[Country:Brazil] map[Country:China]]")
+
+	// numbers of different types are in the same group
+	rows := []map[string]interface{}{{"k": 1}, {"k": int64(1)}, {"k": 1.0}, {"k": uint8(1)}, {"k": 1.5}}
+	err = pipeline.Query("SELECT k, COUNT(*) AS n FROM ? GROUP BY k", rows).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[k:1 n:4] map[k:1.5 n:1]]")
+
+	var cheap []Item
+	err = pipeline.Query("SELECT * FROM ? WHERE Price < 10 ORDER BY Name", items).Out(&cheap)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(cheap)).ToEqual("[{6 Rice China 8} {3 Sugar Brazil 5}]")
+}
+
+func TestQueryJoin(t *testing.T) {
+	e := expect.New(t)
+	sales := []Sale{{2, 3}, {1, 1}, {2, 2}, {9, 1}}
+	var result []map[string]interface{}
+	err := pipeline.Query("SELECT i.Name, SUM(s.Quantity * i.Price) AS revenue FROM ? i JOIN ? AS s ON s.ItemID = i.ID GROUP BY i.Name ORDER BY revenue DESC", items, sales).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[Name:Coffee revenue:125] map[Name:Tea revenue:12]]")
+
+	evalError := pipeline.EvalError{}
+	err = pipeline.Query("SELECT Name FROM ? JOIN ? ON ItemID + 1", items, sales).Out(&result)
+	e.Expect(errors.As(err, &evalError)).ToBeTrue()
+	e.Expect(evalError.Error()).ToEqual("Evaluation error at column 37 : ItemID + 1 is not a boolean expression, got 3")
+
+	statement, err := pipeline.PrepareQuery("SELECT Quantity, Name FROM ? JOIN ? ON ItemID = ID WHERE Quantity > 1")
+	e.Expect(err).ToBeNil()
+	err = statement.In(sales, items).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[map[Name:Coffee Quantity:3] map[Name:Coffee Quantity:2]]")
+
+	err = statement.In(sales).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestPrepareQueryErrors(t *testing.T) {
+	e := expect.New(t)
+	for sql, message := range map[string]string{
+		"SELECT DISTINCT Name FROM ?":                   "Query error at column 8 : DISTINCT is not supported",
+		"SELECT Name FROM ? LEFT JOIN ? ON ItemID = ID": "Query error at column 20 : LEFT is not supported",
+		"SELECT Name FROM items":                        `Query error at column 18 : expected ?, got "items", only ? placeholders are supported in FROM and JOIN`,
+		"SELECT Name FROM (SELECT * FROM ?)":            "Query error at column 18 : subqueries are not supported",
+		"SELECT Name FROM ? WHERE COUNT(*) > 1":         "Query error at column 26 : aggregates are not allowed in WHERE",
+		"SELECT * FROM ? GROUP BY Country":              "Query error at column 1 : SELECT * cannot be grouped",
+		"SELECT Name FROM ? ORDER BY 3":                 "Query error at column 29 : invalid column position 3",
+		"SELECT Name FROM ? LIMIT ten":                  `Parse error at column 26 : expected an integer, got "ten"`,
+		"SELECT Name ? FROM":                            `Parse error at column 13 : expected FROM, got "?"`,
+		"SELECT Name FROM ? UNION SELECT Name FROM ?":   "Query error at column 20 : UNION is not supported",
+		"UPDATE ? SET Price = 1":                        `Parse error at column 1 : expected SELECT, got "UPDATE"`,
+		"SELECT Name FROM ? WHERE Price >":              "Parse error at column 33 : unexpected end of expression",
+	} {
+		_, err := pipeline.PrepareQuery(sql)
+		e.Expect(err).Not().ToBeNil()
+		e.Expect(err.Error()).ToEqual(message)
+	}
+}
+
+func ExampleQuery() {
+	var result []map[string]interface{}
+	err := pipeline.Query("SELECT Country, COUNT(*) FROM ? WHERE Price > 10 GROUP BY Country ORDER BY 2 DESC LIMIT 1", items).Out(&result)
+	fmt.Print(result, " ", err)
+	// Output: [map[COUNT(*):2 Country:Brazil]] <nil>
+}
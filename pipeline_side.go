@@ -0,0 +1,125 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+)
+
+type sideInput struct {
+	name  string
+	value interface{}
+}
+
+// Context gives the callbacks of MapWith, FilterWith and OpWith the side inputs of their pipeline
+type Context struct {
+	values map[string]interface{}
+}
+
+// Get returns the side input named name, nil if there is none
+func (context *Context) Get(name string) interface{} {
+	return context.values[name]
+}
+
+// Lookup returns the side input named name and whether there is one
+func (context *Context) Lookup(name string) (interface{}, bool) {
+	value, ok := context.values[name]
+	return value, ok
+}
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// WithSideInput makes pipelineOrValue available to the callbacks of the pipeline as name,
+// replacing the side input with the same name if any. A *Pipeline runs each time the
+// pipeline runs, before its first step, and its result is the side input:
+//
+//	rates := pipeline.In(quotes).ToMap(byCurrency)
+//	p := pipeline.In(prices).WithSideInput("rates", rates).MapWith(toEuros)
+//
+// Calling WithSideInput again between runs turns the pipeline into a template.
+func (pipeline *Pipeline) WithSideInput(name string, pipelineOrValue interface{}) *Pipeline {
+	for i, input := range pipeline.sideInputs {
+		if input.name == name {
+			pipeline.sideInputs[i].value = pipelineOrValue
+			return pipeline
+		}
+	}
+	pipeline.sideInputs = append(pipeline.sideInputs, sideInput{name, pipelineOrValue})
+	return pipeline
+}
+
+// MapWith is Map with a callback receiving the side inputs of the pipeline
+func (pipeline *Pipeline) MapWith(callback func(context *Context, element interface{}, index int) interface{}) *Pipeline {
+	return pipeline.push("MapWith", func() (interface{}, error) {
+		context := pipeline.context
+		return Map(pipeline.in, func(element interface{}, index int) interface{} {
+			return callback(context, element, index)
+		})
+	}, callback)
+}
+
+// FilterWith is Filter with a predicate receiving the side inputs of the pipeline
+func (pipeline *Pipeline) FilterWith(predicate func(context *Context, element interface{}, index int) bool) *Pipeline {
+	return pipeline.push("FilterWith", func() (interface{}, error) {
+		context := pipeline.context
+		return Filter(pipeline.in, func(element interface{}, index int) bool {
+			return predicate(context, element, index)
+		})
+	}, predicate)
+}
+
+// OpWith is Op with a callback receiving the side inputs of the pipeline
+func (pipeline *Pipeline) OpWith(callback func(context *Context, in interface{}) (interface{}, error)) *Pipeline {
+	return pipeline.push("OpWith", func() (interface{}, error) {
+		return callback(pipeline.context, pipeline.in)
+	}, callback)
+}
+
+// resolveSideInputs runs the pipelines given as side inputs
+func (pipeline *Pipeline) resolveSideInputs() (*Context, error) {
+	context := &Context{values: map[string]interface{}{}}
+	for _, input := range pipeline.sideInputs {
+		value := input.value
+		if side, ok := value.(*Pipeline); ok {
+			var result interface{}
+			if err := side.Out(&result); err != nil {
+				return nil, SideInputError{input.name, err}
+			}
+			value = result
+		}
+		context.values[input.name] = value
+	}
+	return context, nil
+}
+
+// SideInputError is returned when the pipeline of a side input fails
+type SideInputError struct {
+	name string
+	err  error
+}
+
+// Error returns a string
+func (sideInputError SideInputError) Error() string {
+	return fmt.Sprintf("Error in side input %s : %s", sideInputError.name, sideInputError.err)
+}
+
+// Unwrap returns the error of the side input
+func (sideInputError SideInputError) Unwrap() error {
+	return sideInputError.err
+}
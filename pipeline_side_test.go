@@ -0,0 +1,107 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+type Price struct {
+	Amount   float64
+	Currency string
+}
+
+func toEuros(context *pipeline.Context, el interface{}, i int) interface{} {
+	price := el.(Price)
+	return price.Amount * context.Get("rates").(map[string]float64)[price.Currency]
+}
+
+func TestWithSideInput(t *testing.T) {
+	e := expect.New(t)
+	runs := 0
+	rates := pipeline.In([]string{"USD:0.5", "GBP:2"}).Op(func(in interface{}) (interface{}, error) {
+		runs++
+		result := map[string]float64{}
+		for _, quote := range in.([]string) {
+			var rate float64
+			fmt.Sscanf(strings.Split(quote, ":")[1], "%g", &rate)
+			result[strings.Split(quote, ":")[0]] = rate
+		}
+		return result, nil
+	})
+	p := pipeline.In([]Price{{10, "USD"}, {3, "GBP"}, {4, "USD"}}).
+		WithSideInput("rates", rates).
+		WithSideInput("minimum", 5.0).
+		MapWith(toEuros).
+		FilterWith(func(context *pipeline.Context, el interface{}, i int) bool {
+			return el.(float64) >= context.Get("minimum").(float64)
+		})
+	var result []float64
+	e.Expect(p.Out(&result)).ToBeNil()
+	e.Expect(result).ToEqual([]float64{5, 6})
+	e.Expect(runs).ToEqual(1)
+
+	// pipelines run again with other side inputs
+	e.Expect(p.WithSideInput("minimum", 0.0).Out(&result)).ToBeNil()
+	e.Expect(result).ToEqual([]float64{5, 6, 2})
+	e.Expect(runs).ToEqual(2)
+}
+
+func TestOpWith(t *testing.T) {
+	e := expect.New(t)
+	var result interface{}
+	err := pipeline.In([]int{1, 2}).OpWith(func(context *pipeline.Context, in interface{}) (interface{}, error) {
+		_, ok := context.Lookup("missing")
+		return ok, nil
+	}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual(false)
+
+	failure := errors.New("failure")
+	err = pipeline.In([]int{1, 2}).
+		WithSideInput("failing", pipeline.In(1).Op(func(in interface{}) (interface{}, error) { return nil, failure })).
+		MapWith(func(context *pipeline.Context, el interface{}, i int) interface{} { return el }).
+		Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	var sideInputError pipeline.SideInputError
+	e.Expect(errors.As(err, &sideInputError)).ToBeTrue()
+	e.Expect(strings.HasPrefix(err.Error(), "Error in side input failing : Error at step 1 ")).ToBeTrue()
+}
+
+func TestOutRunsAgain(t *testing.T) {
+	e := expect.New(t)
+	p := pipeline.In([]int{3, 1, 2}).Sort(lessInt).Head(1)
+	var first, second []int
+	e.Expect(p.Out(&first)).ToBeNil()
+	e.Expect(p.Out(&second)).ToBeNil()
+	e.Expect(second).ToEqual(first)
+	e.Expect(p.Explain()).ToEqual("In([]int)\n1. Sort(pipeline_test.lessInt)\n2. Head(1)\n")
+}
+
+func ExamplePipeline_WithSideInput() {
+	var result []float64
+	rates := map[string]float64{"USD": 0.5, "GBP": 2}
+	err := pipeline.In([]Price{{10, "USD"}, {3, "GBP"}}).WithSideInput("rates", rates).MapWith(toEuros).Out(&result)
+	fmt.Print(result, " ", err)
+	// Output: [5 6] <nil>
+}
@@ -0,0 +1,297 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"container/heap"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// CheckSorted makes the sorted collection steps of the pipeline verify that their inputs
+// are sorted and fail with a NotSortedError if they are not. It is meant for debugging
+// since BinarySearch then runs in linear time.
+func (pipeline *Pipeline) CheckSorted() *Pipeline {
+	pipeline.checkSorted = true
+	return pipeline
+}
+
+// sorted returns arrays, or a NotSortedError for the first of them not sorted with less
+// if the pipeline checks its sorted inputs. Channels and Iterators can be read once,
+// they are read into slices which are checked and returned in their place.
+func (pipeline *Pipeline) sorted(less func(a, b interface{}) bool, arrays ...interface{}) ([]interface{}, error) {
+	if !pipeline.checkSorted {
+		return arrays, nil
+	}
+	checked := []interface{}{}
+	for _, array := range arrays {
+		if _, ok := array.(Iterator); ok || reflect.ValueOf(array).Kind() == reflect.Chan {
+			array = NewIterable(array).ToArrayOfInterface()
+		}
+		if err := CheckSorted(array, less); err != nil {
+			return nil, err
+		}
+		checked = append(checked, array)
+	}
+	return checked, nil
+}
+
+// MergeSorted merges sorted arrays into a single sorted array
+func (pipeline *Pipeline) MergeSorted(less func(a, b interface{}) bool, arrays ...interface{}) *Pipeline {
+	return pipeline.push("MergeSorted", func() (interface{}, error) {
+		inputs, err := pipeline.sorted(less, append([]interface{}{pipeline.in}, arrays...)...)
+		if err != nil {
+			return nil, err
+		}
+		return MergeSorted(less, inputs...)
+	}, less, arrays)
+}
+
+// BinarySearch returns the index of value in a sorted collection or -1 if value is not found
+func (pipeline *Pipeline) BinarySearch(value interface{}, less func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("BinarySearch", func() (interface{}, error) {
+		inputs, err := pipeline.sorted(less, pipeline.in)
+		if err != nil {
+			return nil, err
+		}
+		return BinarySearch(inputs[0], value, less)
+	}, value, less)
+}
+
+// SortedUnique removes duplicate values from a sorted collection
+func (pipeline *Pipeline) SortedUnique(less func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("SortedUnique", func() (interface{}, error) {
+		inputs, err := pipeline.sorted(less, pipeline.in)
+		if err != nil {
+			return nil, err
+		}
+		return SortedUnique(inputs[0], less)
+	}, less)
+}
+
+// SortedUnion returns the sorted unique values of sorted arrays
+func (pipeline *Pipeline) SortedUnion(less func(a, b interface{}) bool, arrays ...interface{}) *Pipeline {
+	return pipeline.push("SortedUnion", func() (interface{}, error) {
+		inputs, err := pipeline.sorted(less, append([]interface{}{pipeline.in}, arrays...)...)
+		if err != nil {
+			return nil, err
+		}
+		return SortedUnion(less, inputs...)
+	}, less, arrays)
+}
+
+// SortedIntersection returns the sorted unique values included in all sorted arrays
+func (pipeline *Pipeline) SortedIntersection(less func(a, b interface{}) bool, arrays ...interface{}) *Pipeline {
+	return pipeline.push("SortedIntersection", func() (interface{}, error) {
+		inputs, err := pipeline.sorted(less, append([]interface{}{pipeline.in}, arrays...)...)
+		if err != nil {
+			return nil, err
+		}
+		return SortedIntersection(less, inputs...)
+	}, less, arrays)
+}
+
+// SortedDifference returns the elements of a sorted collection not included in the sorted array
+func (pipeline *Pipeline) SortedDifference(array interface{}, less func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("SortedDifference", func() (interface{}, error) {
+		inputs, err := pipeline.sorted(less, pipeline.in, array)
+		if err != nil {
+			return nil, err
+		}
+		return SortedDifference(inputs[0], inputs[1], less)
+	}, array, less)
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// MergeSorted merges arrays sorted with less into a single sorted array.
+// Equal elements are taken from the first arrays first.
+func MergeSorted(less func(a, b interface{}) bool, arrays ...interface{}) (interface{}, error) {
+	iterables, err := sortedIterables(arrays...)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	// the root of the heap is the smallest head of the arrays
+	h := &boundedHeap{worse: func(a, b heapEntry) bool {
+		return less(a.value, b.value) || (!less(b.value, a.value) && a.index < b.index)
+	}}
+	// key holds the position of the element in its array
+	for i, iterable := range iterables {
+		if iterable.Length() > 0 {
+			heap.Push(h, heapEntry{value: iterable.At(0), key: 0, index: i})
+		}
+	}
+	for h.Len() > 0 {
+		entry := h.entries[0]
+		result = append(result, entry.value)
+		if next := entry.key.(int) + 1; next < iterables[entry.index].Length() {
+			h.entries[0] = heapEntry{value: iterables[entry.index].At(next), key: next, index: entry.index}
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return result, nil
+}
+
+// BinarySearch returns the index of the first element equal to value in an array sorted with less,
+// or -1 if value is not found
+func BinarySearch(array interface{}, value interface{}, less func(a, b interface{}) bool) (interface{}, error) {
+	iterables, err := sortedIterables(array)
+	if err != nil {
+		return nil, err
+	}
+	iterable := iterables[0]
+	index := sort.Search(iterable.Length(), func(i int) bool {
+		return !less(iterable.At(i), value)
+	})
+	if index < iterable.Length() && !less(value, iterable.At(index)) {
+		return index, nil
+	}
+	return -1, nil
+}
+
+// SortedUnique removes duplicate values from an array sorted with less.
+// Two elements are equal if neither is less than the other.
+func SortedUnique(array interface{}, less func(a, b interface{}) bool) (interface{}, error) {
+	iterables, err := sortedIterables(array)
+	if err != nil {
+		return nil, err
+	}
+	return sortedUnique(iterables[0].ToArrayOfInterface(), less), nil
+}
+
+// SortedUnion returns the unique values of arrays sorted with less, in linear time
+func SortedUnion(less func(a, b interface{}) bool, arrays ...interface{}) (interface{}, error) {
+	merged, err := MergeSorted(less, arrays...)
+	if err != nil {
+		return nil, err
+	}
+	return sortedUnique(merged.([]interface{}), less), nil
+}
+
+// SortedIntersection returns the unique values included in all arrays sorted with less, in linear time
+func SortedIntersection(less func(a, b interface{}) bool, arrays ...interface{}) (interface{}, error) {
+	iterables, err := sortedIterables(arrays...)
+	if err != nil {
+		return nil, err
+	}
+	if len(iterables) == 0 {
+		return nil, nil
+	}
+	result := sortedUnique(iterables[0].ToArrayOfInterface(), less)
+	for _, iterable := range iterables[1:] {
+		intersection := []interface{}{}
+		for i, j := 0, 0; i < len(result) && j < iterable.Length(); {
+			switch {
+			case less(result[i], iterable.At(j)):
+				i++
+			case less(iterable.At(j), result[i]):
+				j++
+			default:
+				intersection = append(intersection, result[i])
+				i++
+				j++
+			}
+		}
+		result = intersection
+	}
+	return result, nil
+}
+
+// SortedDifference returns the elements of array not included in values,
+// both sorted with less, in linear time
+func SortedDifference(array interface{}, values interface{}, less func(a, b interface{}) bool) (interface{}, error) {
+	iterables, err := sortedIterables(array, values)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	iterable, excluded := iterables[0], iterables[1]
+	for i, j := 0, 0; i < iterable.Length(); {
+		switch {
+		case j >= excluded.Length() || less(iterable.At(i), excluded.At(j)):
+			result = append(result, iterable.At(i))
+			i++
+		case less(excluded.At(j), iterable.At(i)):
+			j++
+		default:
+			i++
+		}
+	}
+	return result, nil
+}
+
+// CheckSorted returns a NotSortedError if array is not sorted with less
+func CheckSorted(array interface{}, less func(a, b interface{}) bool) error {
+	if !IsIterable(array) {
+		return NotIterableError{array}
+	}
+	iterable := NewIterable(array)
+	for i := 1; i < iterable.Length(); i++ {
+		if less(iterable.At(i), iterable.At(i-1)) {
+			return NotSortedError{array, i}
+		}
+	}
+	return nil
+}
+
+// sortedIterables returns the iterables of arrays
+func sortedIterables(arrays ...interface{}) ([]IterableInterface, error) {
+	iterables := []IterableInterface{}
+	for _, array := range arrays {
+		if !IsIterable(array) {
+			return nil, NotIterableError{array}
+		}
+		iterables = append(iterables, NewIterable(array))
+	}
+	return iterables, nil
+}
+
+// sortedUnique removes adjacent duplicates
+func sortedUnique(elements []interface{}, less func(a, b interface{}) bool) []interface{} {
+	result := []interface{}{}
+	for i, element := range elements {
+		if i == 0 || less(result[len(result)-1], element) || less(element, result[len(result)-1]) {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// NotSortedError discriminates a collection that is not sorted
+type NotSortedError struct {
+	array interface{}
+	index int
+}
+
+// Error returns a string
+func (notSortedError NotSortedError) Error() string {
+	return fmt.Sprintf("%#v is not sorted at index %d", notSortedError.array, notSortedError.index)
+}
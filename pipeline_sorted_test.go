@@ -0,0 +1,112 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func TestMergeSorted(t *testing.T) {
+	e := expect.New(t)
+	var result []int
+	err := pipeline.In([]int{1, 4, 7}).MergeSorted(lessInt, []int{2, 5, 8}, []int{}, []int{3, 6, 9, 10}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+}
+
+func TestBinarySearch(t *testing.T) {
+	e := expect.New(t)
+	var index int
+	err := pipeline.In([]int{1, 3, 3, 5, 8}).BinarySearch(3, lessInt).Out(&index)
+	e.Expect(err).ToBeNil()
+	e.Expect(index).ToEqual(1)
+	err = pipeline.In([]int{1, 3, 3, 5, 8}).BinarySearch(4, lessInt).Out(&index)
+	e.Expect(err).ToBeNil()
+	e.Expect(index).ToEqual(-1)
+	err = pipeline.In("abcz").BinarySearch('z', func(a, b interface{}) bool {
+		return a.(rune) < b.(rune)
+	}).Out(&index)
+	e.Expect(err).ToBeNil()
+	e.Expect(index).ToEqual(3)
+}
+
+func TestSortedUnique(t *testing.T) {
+	e := expect.New(t)
+	var result []int
+	err := pipeline.In([]int{1, 1, 2, 3, 3, 3, 4}).SortedUnique(lessInt).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{1, 2, 3, 4}))
+}
+
+func TestSortedSetOperations(t *testing.T) {
+	e := expect.New(t)
+	var result []int
+	err := pipeline.In([]int{1, 2, 2, 5}).SortedUnion(lessInt, []int{2, 3}, []int{4, 5, 6}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{1, 2, 3, 4, 5, 6}))
+
+	err = pipeline.In([]int{1, 2, 2, 4, 5, 7}).SortedIntersection(lessInt, []int{2, 3, 4, 7}, []int{0, 2, 7}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{2, 7}))
+
+	err = pipeline.In([]int{1, 2, 3, 4, 4, 6}).SortedDifference([]int{2, 4, 5}, lessInt).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{1, 3, 6}))
+}
+
+func TestCheckSorted(t *testing.T) {
+	e := expect.New(t)
+	var result []int
+	err := pipeline.In([]int{1, 3, 2}).CheckSorted().SortedUnion(lessInt, []int{2}).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	notSortedError := pipeline.NotSortedError{}
+	e.Expect(errors.As(err, &notSortedError)).ToBeTrue()
+	err = pipeline.In([]int{1, 2, 3}).CheckSorted().SortedUnion(lessInt, []int{3, 2}).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+	err = pipeline.In([]int{1, 2, 3}).CheckSorted().SortedUnion(lessInt, []int{2}).Out(&result)
+	e.Expect(err).ToBeNil()
+	// other pipelines do not check their inputs
+	err = pipeline.In([]int{1, 3, 2}).SortedUnion(lessInt, []int{2}).Out(&result)
+	e.Expect(err).ToBeNil()
+
+	// channels are read once for the check and the step
+	source := make(chan int, 3)
+	for _, n := range []int{1, 1, 2} {
+		source <- n
+	}
+	close(source)
+	err = pipeline.In(source).CheckSorted().SortedUnique(lessInt).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(result).ToEqual([]int{1, 2})
+
+	e.Expect(pipeline.CheckSorted([]int{1, 1, 2}, lessInt)).ToBeNil()
+	e.Expect(pipeline.CheckSorted([]int{2, 1}, lessInt).Error()).ToEqual("[]int{2, 1} is not sorted at index 1")
+}
+
+func ExamplePipeline_MergeSorted() {
+	var result []string
+	err := pipeline.In([]string{"apple", "kiwi"}).MergeSorted(func(a, b interface{}) bool {
+		return a.(string) < b.(string)
+	}, []string{"banana", "cherry", "lemon"}).Out(&result)
+	fmt.Print(result, " ", err)
+	// Output: [apple banana cherry kiwi lemon] <nil>
+}
@@ -0,0 +1,420 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// StepSpec describes a step of a pipeline in a JSON document such as
+//
+//	[{"op":"filter","fn":"isPaid"},{"op":"head","n":10}]
+//
+// Op is the name of a registered operator, Fn the name of a registered function,
+// N a number and Values a list of values, depending on the operator.
+// The filter and map operators accept an expression (see Expr) instead of a function.
+type StepSpec struct {
+	Op     string        `json:"op"`
+	Fn     string        `json:"fn,omitempty"`
+	Expr   string        `json:"expr,omitempty"`
+	N      *int          `json:"n,omitempty"`
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// Operator adds the step described by a StepSpec to a pipeline,
+// functions named by the step are looked up in registry
+type Operator func(pipeline *Pipeline, step StepSpec, registry *Registry) error
+
+// Registry holds the named operators and functions pipeline specs are built from
+type Registry struct {
+	mutex     sync.RWMutex
+	operators map[string]Operator
+	functions map[string]interface{}
+}
+
+// DefaultRegistry is the registry used by LoadSpec and Register
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a registry holding the built-in operators:
+// chunk, compact, filter, first, flatten, groupBy, head, last, map, push, reverse,
+// sort, tail, topK, unique and without. Unlike the Head and Tail methods, head and tail
+// keep at most the first and last n elements.
+func NewRegistry() *Registry {
+	registry := &Registry{operators: map[string]Operator{}, functions: map[string]interface{}{}}
+	for name, operator := range builtinOperators {
+		registry.RegisterOperator(name, operator)
+	}
+	return registry
+}
+
+// Register registers a function under name in the default registry
+func Register(name string, function interface{}) {
+	DefaultRegistry.Register(name, function)
+}
+
+// Register registers a function under name. function is a predicate
+// func(element interface{}, index int) bool, a mapper func(element interface{}, index int) interface{}
+// or a comparison func(a, b interface{}) bool, depending on the operators it is used with.
+func (registry *Registry) Register(name string, function interface{}) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.functions[name] = function
+}
+
+// RegisterOperator registers an operator under name
+func (registry *Registry) RegisterOperator(name string, operator Operator) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.operators[name] = operator
+}
+
+// Predicate returns the predicate named by step.Fn
+func (registry *Registry) Predicate(step StepSpec) (func(element interface{}, index int) bool, error) {
+	function, err := registry.function(step)
+	if err != nil {
+		return nil, err
+	}
+	if predicate, ok := function.(func(element interface{}, index int) bool); ok {
+		return predicate, nil
+	}
+	return nil, fmt.Errorf("function %q is not a func(interface{}, int) bool", step.Fn)
+}
+
+// Mapper returns the mapper named by step.Fn
+func (registry *Registry) Mapper(step StepSpec) (func(element interface{}, index int) interface{}, error) {
+	function, err := registry.function(step)
+	if err != nil {
+		return nil, err
+	}
+	if mapper, ok := function.(func(element interface{}, index int) interface{}); ok {
+		return mapper, nil
+	}
+	return nil, fmt.Errorf("function %q is not a func(interface{}, int) interface{}", step.Fn)
+}
+
+// Less returns the comparison function named by step.Fn
+func (registry *Registry) Less(step StepSpec) (func(a, b interface{}) bool, error) {
+	function, err := registry.function(step)
+	if err != nil {
+		return nil, err
+	}
+	if less, ok := function.(func(a, b interface{}) bool); ok {
+		return less, nil
+	}
+	return nil, fmt.Errorf("function %q is not a func(interface{}, interface{}) bool", step.Fn)
+}
+
+func (registry *Registry) function(step StepSpec) (interface{}, error) {
+	if step.Fn == "" {
+		return nil, fmt.Errorf("missing fn")
+	}
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	function, ok := registry.functions[step.Fn]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", step.Fn)
+	}
+	return function, nil
+}
+
+func (registry *Registry) operator(name string) (Operator, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	operator, ok := registry.operators[name]
+	return operator, ok
+}
+
+/*********************************/
+/*             SPEC              */
+/*********************************/
+
+// Spec is a reusable list of steps that can be applied to any pipeline
+type Spec struct {
+	steps    []StepSpec
+	registry *Registry
+}
+
+// LoadSpec builds a Spec from a JSON document with the default registry
+func LoadSpec(data []byte) (*Spec, error) {
+	return DefaultRegistry.LoadSpec(data)
+}
+
+// LoadSpec builds a Spec from a JSON document, a list of StepSpec.
+// Every step is validated, a SpecError points to the first invalid step.
+func (registry *Registry) LoadSpec(data []byte) (*Spec, error) {
+	steps := []StepSpec{}
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, err
+	}
+	spec := &Spec{steps: steps, registry: registry}
+	if _, err := spec.Apply(In([]interface{}{})); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Apply adds the steps of the spec to pipeline
+func (spec *Spec) Apply(pipeline *Pipeline) (*Pipeline, error) {
+	for i := range spec.steps {
+		// each application gets its own copy so that Spec can tell consecutive steps apart
+		step := spec.steps[i]
+		operator, ok := spec.registry.operator(step.Op)
+		if !ok {
+			return nil, SpecError{i + 1, step.Op, fmt.Errorf("unknown operator %q", step.Op)}
+		}
+		start := len(pipeline.commands)
+		if err := operator(pipeline, step, spec.registry); err != nil {
+			return nil, SpecError{i + 1, step.Op, err}
+		}
+		for j := start; j < len(pipeline.commands); j++ {
+			pipeline.commands[j].spec = &step
+			pipeline.commands[j].registry = spec.registry
+		}
+	}
+	return pipeline, nil
+}
+
+// Run applies the spec to a pipeline reading in and sets output
+func (spec *Spec) Run(in Array, output interface{}) error {
+	pipeline, err := spec.Apply(In(in))
+	if err != nil {
+		return err
+	}
+	return pipeline.Out(output)
+}
+
+// Steps returns the steps of the spec
+func (spec *Spec) Steps() []StepSpec {
+	return append([]StepSpec{}, spec.steps...)
+}
+
+// MarshalJSON serialises the spec as a list of StepSpec
+func (spec *Spec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(spec.steps)
+}
+
+// Spec returns the spec of a pipeline built from a Spec, so that it can be serialised.
+// The spec uses the registry the steps were built with, the default registry if there are none.
+// Steps added with Go callbacks or built with different registries cannot be serialised.
+func (pipeline *Pipeline) Spec() (*Spec, error) {
+	spec := &Spec{steps: []StepSpec{}, registry: DefaultRegistry}
+	if len(pipeline.commands) > 0 && pipeline.commands[0].registry != nil {
+		spec.registry = pipeline.commands[0].registry
+	}
+	var last *StepSpec
+	for i, command := range pipeline.commands {
+		if command.spec == nil {
+			return nil, SpecError{i + 1, command.name, fmt.Errorf("step was not built from a spec")}
+		}
+		if command.registry != spec.registry {
+			return nil, SpecError{i + 1, command.name, fmt.Errorf("step was built with another registry")}
+		}
+		// an operator may add several commands for a single step
+		if command.spec != last {
+			spec.steps = append(spec.steps, *command.spec)
+			last = command.spec
+		}
+	}
+	return spec, nil
+}
+
+/*********************************/
+/*           OPERATORS           */
+/*********************************/
+
+var builtinOperators = map[string]Operator{
+	"chunk": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		n, err := specN(step)
+		if err != nil {
+			return err
+		}
+		pipeline.Chunk(n)
+		return nil
+	},
+	"compact": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Compact()
+		return nil
+	},
+	"filter": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		if step.Expr != "" {
+			if _, err := Compile(step.Expr); err != nil {
+				return err
+			}
+			pipeline.FilterExpr(step.Expr)
+			return nil
+		}
+		predicate, err := registry.Predicate(step)
+		if err != nil {
+			return err
+		}
+		pipeline.Filter(predicate)
+		return nil
+	},
+	"first": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.First()
+		return nil
+	},
+	"flatten": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Flatten()
+		return nil
+	},
+	"groupBy": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		mapper, err := registry.Mapper(step)
+		if err != nil {
+			return err
+		}
+		pipeline.GroupBy(mapper)
+		return nil
+	},
+	"head": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		n, err := specN(step)
+		if err != nil {
+			return err
+		}
+		// not a Head step, which fails when the collection is shorter
+		pipeline.push("Take", func() (interface{}, error) {
+			return take(pipeline.in, 0, n)
+		}, n)
+		return nil
+	},
+	"last": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Last()
+		return nil
+	},
+	"map": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		if step.Expr != "" {
+			if _, err := Compile(step.Expr); err != nil {
+				return err
+			}
+			pipeline.MapExpr(step.Expr)
+			return nil
+		}
+		mapper, err := registry.Mapper(step)
+		if err != nil {
+			return err
+		}
+		pipeline.Map(mapper)
+		return nil
+	},
+	"push": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Push(step.Values...)
+		return nil
+	},
+	"reverse": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Reverse()
+		return nil
+	},
+	"sort": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		less, err := registry.Less(step)
+		if err != nil {
+			return err
+		}
+		pipeline.Sort(less)
+		return nil
+	},
+	"tail": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		n, err := specN(step)
+		if err != nil {
+			return err
+		}
+		pipeline.push("TakeLast", func() (interface{}, error) {
+			return take(pipeline.in, -n, n)
+		}, n)
+		return nil
+	},
+	"topK": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		n, err := specN(step)
+		if err != nil {
+			return err
+		}
+		less, err := registry.Less(step)
+		if err != nil {
+			return err
+		}
+		pipeline.TopK(n, less)
+		return nil
+	},
+	"unique": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Unique()
+		return nil
+	},
+	"without": func(pipeline *Pipeline, step StepSpec, registry *Registry) error {
+		pipeline.Without(step.Values...)
+		return nil
+	},
+}
+
+// take returns at most count elements of array from start, a negative start counts from the end.
+// Unlike Head and Tail it does not fail when array is too short.
+func take(array interface{}, start int, count int) (interface{}, error) {
+	if !IsIterable(array) {
+		return nil, NotIterableError{array}
+	}
+	iterable := NewIterable(array)
+	if start < 0 {
+		start = iterable.Length() + start
+	}
+	if start < 0 {
+		start = 0
+	}
+	result := []interface{}{}
+	for i := start; i < iterable.Length() && i < start+count; i++ {
+		result = append(result, iterable.At(i))
+	}
+	return result, nil
+}
+
+func specN(step StepSpec) (int, error) {
+	if step.N == nil {
+		return 0, fmt.Errorf("missing n")
+	}
+	if *step.N < 0 {
+		return 0, fmt.Errorf("n must be positive, got %d", *step.N)
+	}
+	return *step.N, nil
+}
+
+// Operators returns the names of the operators of the registry, sorted
+func (registry *Registry) Operators() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	names := []string{}
+	for name := range registry.operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// SpecError discriminates an invalid step of a pipeline spec
+type SpecError struct {
+	step   int
+	op     string
+	reason error
+}
+
+// Error returns a string
+func (specError SpecError) Error() string {
+	return fmt.Sprintf("Invalid step %d (%s) : %s", specError.step, specError.op, specError.reason)
+}
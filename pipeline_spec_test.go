@@ -0,0 +1,136 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func specRegistry() *pipeline.Registry {
+	registry := pipeline.NewRegistry()
+	registry.Register("isPaid", func(el interface{}, i int) bool { return el.(Invoice).Paid })
+	registry.Register("customer", func(el interface{}, i int) interface{} { return el.(Invoice).Customer })
+	registry.Register("byAmount", func(a, b interface{}) bool { return a.(Invoice).Amount < b.(Invoice).Amount })
+	return registry
+}
+
+type Invoice struct {
+	Customer string
+	Amount   int
+	Paid     bool
+}
+
+func TestLoadSpec(t *testing.T) {
+	e := expect.New(t)
+	spec, err := specRegistry().LoadSpec([]byte(`[{"op":"filter","fn":"isPaid"},{"op":"sort","fn":"byAmount"},{"op":"head","n":2}]`))
+	e.Expect(err).ToBeNil()
+	invoices := []Invoice{{"bob", 30, true}, {"alice", 10, false}, {"carol", 20, true}, {"dave", 5, true}}
+	var result []Invoice
+	e.Expect(spec.Run(invoices, &result)).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[{dave 5 true} {carol 20 true}]")
+
+	// a spec is reusable
+	e.Expect(spec.Run(invoices[:2], &result)).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[{bob 30 true}]")
+}
+
+func TestLoadSpecErrors(t *testing.T) {
+	e := expect.New(t)
+	registry := specRegistry()
+	for document, message := range map[string]string{
+		`[{"op":"filter","fn":"isPaid"},{"op":"explode"}]`: `Invalid step 2 (explode) : unknown operator "explode"`,
+		`[{"op":"head"}]`:                                   `Invalid step 1 (head) : missing n`,
+		`[{"op":"head","n":-1}]`:                            `Invalid step 1 (head) : n must be positive, got -1`,
+		`[{"op":"unique"},{"op":"filter","fn":"missing"}]`:  `Invalid step 2 (filter) : unknown function "missing"`,
+		`[{"op":"unique"},{"op":"filter","fn":"customer"}]`: `Invalid step 2 (filter) : function "customer" is not a func(interface{}, int) bool`,
+	} {
+		_, err := registry.LoadSpec([]byte(document))
+		e.Expect(err).Not().ToBeNil()
+		e.Expect(err.Error()).ToEqual(message)
+		_, ok := err.(pipeline.SpecError)
+		e.Expect(ok).ToBeTrue()
+	}
+	_, err := registry.LoadSpec([]byte(`{"op":"head"}`))
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestRegistryOperator(t *testing.T) {
+	e := expect.New(t)
+	registry := specRegistry()
+	registry.RegisterOperator("paidCustomers", func(p *pipeline.Pipeline, step pipeline.StepSpec, registry *pipeline.Registry) error {
+		p.Filter(func(el interface{}, i int) bool { return el.(Invoice).Paid }).
+			Map(func(el interface{}, i int) interface{} { return el.(Invoice).Customer })
+		return nil
+	})
+	spec, err := registry.LoadSpec([]byte(`[{"op":"paidCustomers"},{"op":"reverse"}]`))
+	e.Expect(err).ToBeNil()
+	var result []string
+	e.Expect(spec.Run([]Invoice{{"bob", 30, true}, {"alice", 10, false}, {"carol", 20, true}}, &result)).ToBeNil()
+	e.Expect(strings.Join(result, ",")).ToEqual("carol,bob")
+}
+
+func TestPipelineSpec(t *testing.T) {
+	e := expect.New(t)
+	document := `[{"op":"unique"},{"op":"groupBy","fn":"customer"},{"op":"without","values":[1]},{"op":"head","n":1},{"op":"head","n":1}]`
+	spec, err := specRegistry().LoadSpec([]byte(document))
+	e.Expect(err).ToBeNil()
+	p, err := spec.Apply(pipeline.In([]Invoice{}))
+	e.Expect(err).ToBeNil()
+	serialised, err := p.Spec()
+	e.Expect(err).ToBeNil()
+	data, err := json.Marshal(serialised)
+	e.Expect(err).ToBeNil()
+	e.Expect(string(data)).ToEqual(document)
+	// the functions of the spec are looked up in the registry of the pipeline
+	var result []interface{}
+	e.Expect(serialised.Run([]Invoice{{"bob", 30, true}}, &result)).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual("[[{bob 30 true}]]")
+
+	other, err := pipeline.NewRegistry().LoadSpec([]byte(`[{"op":"reverse"}]`))
+	e.Expect(err).ToBeNil()
+	mixed, err := spec.Apply(pipeline.In([]Invoice{}))
+	e.Expect(err).ToBeNil()
+	mixed, err = other.Apply(mixed)
+	e.Expect(err).ToBeNil()
+	_, err = mixed.Spec()
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Invalid step 6 (Reverse) : step was built with another registry")
+
+	_, err = p.Filter(func(el interface{}, i int) bool { return true }).Spec()
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Invalid step 6 (Filter) : step was not built from a spec")
+}
+
+func ExampleLoadSpec() {
+	pipeline.Register("isEven", func(el interface{}, i int) bool {
+		return int(el.(float64))%2 == 0
+	})
+	spec, err := pipeline.LoadSpec([]byte(`[{"op":"filter","fn":"isEven"},{"op":"head","n":2}]`))
+	fmt.Println(err)
+	var result []float64
+	err = spec.Run([]float64{1, 2, 3, 4, 5, 6}, &result)
+	fmt.Print(result, " ", err)
+	// Output:
+	// <nil>
+	// [2 4] <nil>
+}
@@ -0,0 +1,151 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+/*********************************/
+/*            SOURCES            */
+/*********************************/
+
+// FromRows returns a Pipeline reading database rows lazily.
+// Rows are scanned into map[string]interface{} keyed by column name if targetOrNil is nil,
+// or into values of the type of targetOrNil, a struct or a pointer to a struct whose fields
+// are matched with the columns by `db:"column"` tag or by name.
+// rows are closed once read, when an error occurs or when the pipeline stops reading them
+// early, rows.Err() is reported as a StepError.
+func FromRows(rows *sql.Rows, targetOrNil interface{}) *Pipeline {
+	return In(&rowsIterator{rows: rows, target: targetOrNil})
+}
+
+// rowsIterator implements Iterator
+type rowsIterator struct {
+	rows    *sql.Rows
+	target  interface{}
+	columns []string
+	fields  []structField
+	closed  bool
+	value   interface{}
+	err     error
+}
+
+// Next scans the next row
+func (iterator *rowsIterator) Next() bool {
+	if iterator.closed {
+		return false
+	}
+	if !iterator.rows.Next() {
+		iterator.close(iterator.rows.Err())
+		return false
+	}
+	if iterator.columns == nil {
+		columns, err := iterator.rows.Columns()
+		if err != nil {
+			iterator.close(err)
+			return false
+		}
+		iterator.columns = columns
+	}
+	value, err := iterator.scan()
+	if err != nil {
+		iterator.close(err)
+		return false
+	}
+	iterator.value = value
+	return true
+}
+
+// Value returns the current row
+func (iterator *rowsIterator) Value() interface{} {
+	return iterator.value
+}
+
+// Err returns the error that stopped the iteration
+func (iterator *rowsIterator) Err() error {
+	return iterator.err
+}
+
+// Close closes the rows if they are not read entirely
+func (iterator *rowsIterator) Close() error {
+	if !iterator.closed {
+		iterator.close(nil)
+	}
+	return iterator.err
+}
+
+func (iterator *rowsIterator) close(err error) {
+	iterator.closed = true
+	closeErr := iterator.rows.Close()
+	if err == nil {
+		err = closeErr
+	}
+	iterator.err = err
+}
+
+func (iterator *rowsIterator) scan() (interface{}, error) {
+	destinations := make([]interface{}, len(iterator.columns))
+	if iterator.target == nil {
+		values := make([]interface{}, len(iterator.columns))
+		for i := range values {
+			destinations[i] = &values[i]
+		}
+		if err := iterator.rows.Scan(destinations...); err != nil {
+			return nil, err
+		}
+		result := map[string]interface{}{}
+		for i, column := range iterator.columns {
+			if bytes, ok := values[i].([]byte); ok {
+				values[i] = string(bytes)
+			}
+			result[column] = values[i]
+		}
+		return result, nil
+	}
+	targetType := reflect.TypeOf(iterator.target)
+	structType := targetType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, CannotAssignError{iterator.columns, iterator.target}
+	}
+	target := reflect.New(structType)
+	if iterator.fields == nil {
+		iterator.fields = structFields(structType, "db")
+	}
+	for i, column := range iterator.columns {
+		var discard interface{}
+		destinations[i] = &discard
+		for _, field := range iterator.fields {
+			if strings.EqualFold(field.name, column) {
+				destinations[i] = target.Elem().FieldByIndex(field.index).Addr().Interface()
+				break
+			}
+		}
+	}
+	if err := iterator.rows.Scan(destinations...); err != nil {
+		return nil, err
+	}
+	if targetType.Kind() == reflect.Ptr {
+		return target.Interface(), nil
+	}
+	return target.Elem().Interface(), nil
+}
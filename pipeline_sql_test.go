@@ -0,0 +1,160 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+/*********************************/
+/*          FAKE DRIVER          */
+/*********************************/
+
+// fakeDriver returns the same rows for every query, the query "broken" fails after the first row
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return 0 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (stmt fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{query: stmt.query, closed: &fakeRowsClosed}, nil
+}
+
+var fakeRowsClosed bool
+
+type fakeRows struct {
+	query  string
+	index  int
+	closed *bool
+}
+
+func (rows *fakeRows) Columns() []string { return []string{"id", "product_name", "price"} }
+func (rows *fakeRows) Close() error      { *rows.closed = true; return nil }
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	data := [][]driver.Value{{int64(1), []byte("Iphone 6"), 500.0}, {int64(2), []byte("HTC one"), 300.0}}
+	if rows.query == "broken" && rows.index == 1 {
+		return errors.New("connection lost")
+	}
+	if rows.index >= len(data) {
+		return io.EOF
+	}
+	copy(dest, data[rows.index])
+	rows.index++
+	return nil
+}
+
+func init() {
+	sql.Register("pipeline_fake", fakeDriver{})
+}
+
+type SQLProduct struct {
+	ID    int
+	Name  string `db:"product_name"`
+	Price float64
+}
+
+func TestFromRows(t *testing.T) {
+	e := expect.New(t)
+	db, err := sql.Open("pipeline_fake", "")
+	e.Expect(err).ToBeNil()
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, product_name, price FROM products")
+	e.Expect(err).ToBeNil()
+	fakeRowsClosed = false
+	var products []SQLProduct
+	err = pipeline.FromRows(rows, SQLProduct{}).
+		Filter(func(el interface{}, i int) bool { return el.(SQLProduct).Price < 400 }).
+		Out(&products)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(products)).ToEqual(1)
+	e.Expect(products[0].Name).ToEqual("HTC one")
+	e.Expect(products[0].ID).ToEqual(2)
+	e.Expect(fakeRowsClosed).ToBeTrue()
+
+	rows, err = db.Query("SELECT id, product_name, price FROM products")
+	e.Expect(err).ToBeNil()
+	var records []map[string]interface{}
+	err = pipeline.FromRows(rows, nil).Out(&records)
+	e.Expect(err).ToBeNil()
+	e.Expect(records[0]["product_name"]).ToEqual("Iphone 6")
+	e.Expect(records[1]["id"]).ToEqual(int64(2))
+}
+
+func TestFromRowsError(t *testing.T) {
+	e := expect.New(t)
+	db, err := sql.Open("pipeline_fake", "")
+	e.Expect(err).ToBeNil()
+	defer db.Close()
+	rows, err := db.Query("broken")
+	e.Expect(err).ToBeNil()
+	fakeRowsClosed = false
+	var products []*SQLProduct
+	err = pipeline.FromRows(rows, &SQLProduct{}).Out(&products)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(fakeRowsClosed).ToBeTrue()
+}
+
+func TestFromRowsStopsEarly(t *testing.T) {
+	e := expect.New(t)
+	db, err := sql.Open("pipeline_fake", "")
+	e.Expect(err).ToBeNil()
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, product_name, price FROM products")
+	e.Expect(err).ToBeNil()
+	fakeRowsClosed = false
+	err = pipeline.FromRows(rows, SQLProduct{}).ToWriter(failingWriter{}, nil)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(fakeRowsClosed).ToBeTrue()
+
+	rows, err = db.Query("SELECT id, product_name, price FROM products")
+	e.Expect(err).ToBeNil()
+	fakeRowsClosed = false
+	var first SQLProduct
+	err = pipeline.FromRows(rows, SQLProduct{}).Optimize().
+		Filter(func(el interface{}, i int) bool { return true }).
+		First().
+		Out(&first)
+	e.Expect(err).ToBeNil()
+	e.Expect(first.ID).ToEqual(1)
+	e.Expect(fakeRowsClosed).ToBeTrue()
+}
+
+// failingWriter fails on every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
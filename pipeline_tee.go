@@ -0,0 +1,152 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// tee runs a pipeline once for all the branches reading its result
+type tee struct {
+	once   sync.Once
+	parent *Pipeline
+	result interface{}
+	err    error
+}
+
+// output runs the parent pipeline once and returns its result and error
+func (tee *tee) output() (interface{}, error) {
+	tee.once.Do(func() {
+		tee.err = tee.parent.Out(&tee.result)
+	})
+	return tee.result, tee.err
+}
+
+// run returns a copy of the result of the parent pipeline. The StepError of a failing
+// parent becomes a ParentError, the branch fails at its Tee step with the step and
+// the reason of the parent.
+func (tee *tee) run() (interface{}, error) {
+	result, err := tee.output()
+	if stepError, ok := err.(StepError); ok {
+		if reason, ok := stepError.reason.(error); ok {
+			return nil, attemptsFailure{stepError.attempts, ParentError{stepError.step, reason}}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return copySlice(result), nil
+}
+
+// copySlice returns a copy of value if it is a slice, value otherwise. The copy is shallow:
+// elements such as maps, slices and pointers are shared with value.
+func copySlice(value interface{}) interface{} {
+	if slice := reflect.ValueOf(value); slice.Kind() == reflect.Slice {
+		copied := reflect.MakeSlice(slice.Type(), slice.Len(), slice.Len())
+		reflect.Copy(copied, slice)
+		return copied.Interface()
+	}
+	return value
+}
+
+// Tee returns n pipelines starting with the result of pipeline. pipeline runs once,
+// when the first of them runs, so channels and Iterators are read once for all of them.
+// Tee does not stream: the result of pipeline is held in memory, lazy sources are read
+// entirely, and each branch starts with its own shallow copy of it. A branch may reorder,
+// add or remove elements without changing the others, but elements that are maps, slices
+// or pointers, such as the groups of GroupBy, are shared and must not be modified.
+// The first step of each branch is Tee, it fails with a ParentError if pipeline fails.
+func (pipeline *Pipeline) Tee(n int) []*Pipeline {
+	return pipeline.tee(&tee{parent: pipeline}, n)
+}
+
+func (pipeline *Pipeline) tee(shared *tee, n int) []*Pipeline {
+	branches := []*Pipeline{}
+	for i := 0; i < n; i++ {
+		branch := &Pipeline{commands: []command{}}
+		branches = append(branches, branch.push("Tee", shared.run, pipeline))
+	}
+	return branches
+}
+
+// Branch runs pipeline once and passes a pipeline starting with its result to each branch,
+// for instance to read both a list and an aggregate of the same source:
+//
+//	err := pipeline.In(orders).Filter(isPaid).Branch(
+//		func(paid *pipeline.Pipeline) error { return paid.Out(&list) },
+//		func(paid *pipeline.Pipeline) error { return paid.Reduce(sum, 0).Out(&total) },
+//	)
+//
+// Like Tee, Branch holds the result of pipeline in memory and gives each branch a shallow copy.
+// Every branch runs even if some fail, the error joins the BranchErrors of the failed ones.
+// If pipeline fails, no branch runs and its error is returned.
+func (pipeline *Pipeline) Branch(branches ...func(branch *Pipeline) error) error {
+	shared := &tee{parent: pipeline}
+	outputs := pipeline.tee(shared, len(branches))
+	if len(outputs) > 0 {
+		if _, err := shared.output(); err != nil {
+			return err
+		}
+	}
+	errs := []error{}
+	for i, branch := range branches {
+		if err := branch(outputs[i]); err != nil {
+			errs = append(errs, BranchError{i, err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BranchError is the error of a branch of Branch, numbered from 0
+type BranchError struct {
+	branch int
+	err    error
+}
+
+// Error returns a string
+func (branchError BranchError) Error() string {
+	return fmt.Sprintf("Error in branch %d : %s", branchError.branch, branchError.err)
+}
+
+// Unwrap returns the error of the branch
+func (branchError BranchError) Unwrap() error {
+	return branchError.err
+}
+
+// ParentError is the error of a Tee branch whose parent pipeline failed
+type ParentError struct {
+	step int
+	err  error
+}
+
+// Error returns a string
+func (parentError ParentError) Error() string {
+	return fmt.Sprintf("Error at step %d of the parent pipeline : %s", parentError.step, parentError.err)
+}
+
+// Step returns the failing step of the parent pipeline
+func (parentError ParentError) Step() int {
+	return parentError.step
+}
+
+// Unwrap returns the error of the failing step of the parent pipeline
+func (parentError ParentError) Unwrap() error {
+	return parentError.err
+}
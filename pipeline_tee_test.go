@@ -0,0 +1,125 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func sum(result, el interface{}, i int) interface{} {
+	return result.(int) + el.(int)
+}
+
+func TestTee(t *testing.T) {
+	e := expect.New(t)
+	source := make(chan int, 4)
+	for _, n := range []int{4, 1, 3, 2} {
+		source <- n
+	}
+	close(source)
+	calls := 0
+	branches := pipeline.In(source).Filter(func(el interface{}, i int) bool {
+		calls++
+		return el.(int) > 1
+	}).Tee(2)
+	e.Expect(len(branches)).ToEqual(2)
+	e.Expect(branches[1].Explain()).ToEqual("In(nil)\n1. Tee(pipeline)\n")
+	// the pipeline is drawn as a branch of the Tee step
+	e.Expect(strings.Contains(branches[0].DOT(), "step1_1_step1 -> step1 [style=dashed];")).ToBeTrue()
+	var sorted []int
+	var total int
+	e.Expect(branches[0].Sort(lessInt).Out(&sorted)).ToBeNil()
+	e.Expect(branches[1].Reduce(sum, 0).Out(&total)).ToBeNil()
+	e.Expect(sorted).ToEqual([]int{2, 3, 4})
+	e.Expect(total).ToEqual(9)
+	e.Expect(calls).ToEqual(4)
+}
+
+func TestTeeParentError(t *testing.T) {
+	e := expect.New(t)
+	failure := errors.New("failure")
+	branches := pipeline.In([]int{1, 2}).Reverse().Op(func(in interface{}) (interface{}, error) {
+		return nil, failure
+	}, pipeline.Retry(2, 0)).Tee(2)
+	var list []int
+	err := branches[1].Reverse().Out(&list)
+	var stepError pipeline.StepError
+	e.Expect(errors.As(err, &stepError)).ToBeTrue()
+	e.Expect(errors.Is(err, failure)).ToBeTrue()
+	e.Expect(stepError.Attempts()).ToEqual(2)
+	e.Expect(strings.HasPrefix(err.Error(), "Error at step 1 ")).ToBeTrue()
+	// the branch tells which step of the parent failed
+	var parentError pipeline.ParentError
+	e.Expect(errors.As(err, &parentError)).ToBeTrue()
+	e.Expect(parentError.Step()).ToEqual(2)
+	e.Expect(parentError.Error()).ToEqual("Error at step 2 of the parent pipeline : failure")
+}
+
+func TestBranch(t *testing.T) {
+	e := expect.New(t)
+	var list []int
+	var total int
+	err := pipeline.In([]int{1, 2, 3, 4}).Filter(func(el interface{}, i int) bool { return el.(int)%2 == 0 }).Branch(
+		func(even *pipeline.Pipeline) error {
+			list = append(list, 0)
+			return even.Out(&list)
+		},
+		func(even *pipeline.Pipeline) error { return even.Reduce(sum, 0).Out(&total) },
+	)
+	e.Expect(err).ToBeNil()
+	e.Expect(list).ToEqual([]int{2, 4})
+	e.Expect(total).ToEqual(6)
+
+	failure := errors.New("failure")
+	err = pipeline.In([]int{1, 2}).Branch(
+		func(p *pipeline.Pipeline) error { return p.Head(5).Out(&list) },
+		func(p *pipeline.Pipeline) error { return nil },
+		func(p *pipeline.Pipeline) error { return failure },
+	)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(errors.Is(err, failure)).ToBeTrue()
+	lines := strings.Split(err.Error(), "\n")
+	e.Expect(len(lines)).ToEqual(2)
+	e.Expect(strings.HasPrefix(lines[0], "Error in branch 0 : Error at step 2 ")).ToBeTrue()
+	e.Expect(lines[1]).ToEqual("Error in branch 2 : failure")
+
+	// the error of the pipeline is returned once
+	err = pipeline.In(1).Reverse().Branch(
+		func(p *pipeline.Pipeline) error { return p.Out(&list) },
+		func(p *pipeline.Pipeline) error { return p.Out(&list) },
+	)
+	e.Expect(strings.HasPrefix(err.Error(), "Error at step 1 ")).ToBeTrue()
+}
+
+func ExamplePipeline_Branch() {
+	var paid []int
+	var total int
+	err := pipeline.In([]int{120, -30, 45}).Filter(func(el interface{}, i int) bool {
+		return el.(int) > 0
+	}).Branch(
+		func(p *pipeline.Pipeline) error { return p.Out(&paid) },
+		func(p *pipeline.Pipeline) error { return p.Reduce(sum, 0).Out(&total) },
+	)
+	fmt.Print(paid, " ", total, " ", err)
+	// Output: [120 45] 165 <nil>
+}
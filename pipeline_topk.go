@@ -0,0 +1,214 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"container/heap"
+	"sort"
+)
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// TopK returns the k greatest elements, greatest first
+func (pipeline *Pipeline) TopK(k int, less func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("TopK", func() (interface{}, error) {
+		return TopK(pipeline.in, k, less)
+	}, k, less)
+}
+
+// BottomK returns the k smallest elements, smallest first
+func (pipeline *Pipeline) BottomK(k int, less func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("BottomK", func() (interface{}, error) {
+		return BottomK(pipeline.in, k, less)
+	}, k, less)
+}
+
+// NthElement returns the element that would be at index n if the collection was sorted
+func (pipeline *Pipeline) NthElement(n int, less func(a, b interface{}) bool) *Pipeline {
+	return pipeline.push("NthElement", func() (interface{}, error) {
+		return NthElement(pipeline.in, n, less)
+	}, n, less)
+}
+
+// TopKBy returns the k elements with the greatest keys, greatest first
+func (pipeline *Pipeline) TopKBy(k int, keyFn func(element interface{}, index int) interface{}) *Pipeline {
+	return pipeline.push("TopKBy", func() (interface{}, error) {
+		return TopKBy(pipeline.in, k, keyFn)
+	}, k, keyFn)
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// TopK returns the k greatest elements of an array, greatest first.
+// Only k elements are kept in memory, so channels and Iterators are read lazily.
+// Equal elements keep their original order.
+func TopK(array interface{}, k int, less func(a, b interface{}) bool) (interface{}, error) {
+	return selectK(array, k, func(a, b heapEntry) bool {
+		return less(a.value, b.value) || (!less(b.value, a.value) && a.index > b.index)
+	}, nil)
+}
+
+// BottomK returns the k smallest elements of an array, smallest first.
+// Only k elements are kept in memory, so channels and Iterators are read lazily.
+// Equal elements keep their original order.
+func BottomK(array interface{}, k int, less func(a, b interface{}) bool) (interface{}, error) {
+	return selectK(array, k, func(a, b heapEntry) bool {
+		return less(b.value, a.value) || (!less(a.value, b.value) && a.index > b.index)
+	}, nil)
+}
+
+// TopKBy returns the k elements of an array with the greatest keys, greatest first.
+// keys are numbers, strings or booleans returned by keyFn.
+func TopKBy(array interface{}, k int, keyFn func(element interface{}, index int) interface{}) (interface{}, error) {
+	var Error error
+	result, err := selectK(array, k, func(a, b heapEntry) bool {
+		order, err := compare(a.key, b.key)
+		if err != nil && Error == nil {
+			Error = err
+		}
+		return order < 0 || (order == 0 && a.index > b.index)
+	}, keyFn)
+	if err != nil {
+		return nil, err
+	}
+	if Error != nil {
+		return nil, Error
+	}
+	return result, nil
+}
+
+// NthElement returns the element that would be at index n if the array was sorted with less.
+// It runs in linear time on average.
+func NthElement(array interface{}, n int, less func(a, b interface{}) bool) (interface{}, error) {
+	if !IsIterable(array) {
+		return nil, NotIterableError{array}
+	}
+	elements := NewIterable(array).ToArrayOfInterface()
+	if n < 0 || n >= len(elements) {
+		return nil, IndexOutOfBoundsError{n}
+	}
+	left, right := 0, len(elements)-1
+	for left < right {
+		pivot := elements[left+(right-left)/2]
+		i, j := left, right
+		for i <= j {
+			for less(elements[i], pivot) {
+				i++
+			}
+			for less(pivot, elements[j]) {
+				j--
+			}
+			if i <= j {
+				elements[i], elements[j] = elements[j], elements[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case n <= j:
+			right = j
+		case n >= i:
+			left = i
+		default:
+			return elements[n], nil
+		}
+	}
+	return elements[n], nil
+}
+
+// selectK keeps the k best elements of array in a heap whose root is the worst kept element
+func selectK(array interface{}, k int, worse func(a, b heapEntry) bool, keyFn func(element interface{}, index int) interface{}) (interface{}, error) {
+	if !IsIterable(array) {
+		return nil, NotIterableError{array}
+	}
+	if k < 0 {
+		return nil, IndexOutOfBoundsError{k}
+	}
+	h := &boundedHeap{worse: worse}
+	err := each(array, func(element interface{}, index int) bool {
+		entry := heapEntry{value: element, index: index}
+		if keyFn != nil {
+			entry.key = keyFn(element, index)
+		}
+		switch {
+		case len(h.entries) < k:
+			heap.Push(h, entry)
+		case k > 0 && worse(h.entries[0], entry):
+			h.entries[0] = entry
+			heap.Fix(h, 0)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(h.entries, func(i, j int) bool {
+		return worse(h.entries[j], h.entries[i])
+	})
+	result := []interface{}{}
+	for _, entry := range h.entries {
+		result = append(result, entry.value)
+	}
+	return result, nil
+}
+
+/*********************************/
+/*             HEAP              */
+/*********************************/
+
+type heapEntry struct {
+	value interface{}
+	key   interface{}
+	index int
+}
+
+// boundedHeap implements heap.Interface
+type boundedHeap struct {
+	entries []heapEntry
+	worse   func(a, b heapEntry) bool
+}
+
+// Len returns the length of the heap
+func (h *boundedHeap) Len() int {
+	return len(h.entries)
+}
+
+// Less puts the worst entry at the root of the heap
+func (h *boundedHeap) Less(i, j int) bool {
+	return h.worse(h.entries[i], h.entries[j])
+}
+
+// Swap swaps 2 entries
+func (h *boundedHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+}
+
+// Push adds an entry
+func (h *boundedHeap) Push(entry interface{}) {
+	h.entries = append(h.entries, entry.(heapEntry))
+}
+
+// Pop removes the last entry
+func (h *boundedHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
@@ -0,0 +1,104 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+func lessInt(a, b interface{}) bool {
+	return a.(int) < b.(int)
+}
+
+func TestTopK(t *testing.T) {
+	e := expect.New(t)
+	var result []int
+	err := pipeline.In([]int{5, 1, 9, 3, 7, 9}).TopK(3, lessInt).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{9, 9, 7}))
+
+	err = pipeline.In([]int{2, 1}).TopK(5, lessInt).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(fmt.Sprint(result)).ToEqual(fmt.Sprint([]int{2, 1}))
+
+	err = pipeline.In([]int{2, 1}).TopK(-1, lessInt).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestBottomK(t *testing.T) {
+	e := expect.New(t)
+	var result []Product
+	products := Products{{0, "Iphone 6", 0, 500}, {1, "HTC one", 0, 300}, {2, "Apple Watch", 1, 600}, {3, "ThinkPad", 2, 300}}
+	err := pipeline.In(products).BottomK(2, func(a, b interface{}) bool {
+		return a.(Product).Price < b.(Product).Price
+	}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(result)).ToEqual(2)
+	// equal elements keep their original order
+	e.Expect(result[0].Name).ToEqual("HTC one")
+	e.Expect(result[1].Name).ToEqual("ThinkPad")
+}
+
+func TestNthElement(t *testing.T) {
+	e := expect.New(t)
+	for n, expected := range []int{1, 2, 3, 4, 5, 6, 7} {
+		var result int
+		err := pipeline.In([]int{4, 7, 1, 6, 2, 5, 3}).NthElement(n, lessInt).Out(&result)
+		e.Expect(err).ToBeNil()
+		e.Expect(result).ToEqual(expected)
+	}
+	var result int
+	err := pipeline.In([]int{1}).NthElement(1, lessInt).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestTopKBy(t *testing.T) {
+	e := expect.New(t)
+	orders := make(chan Product)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			orders <- Product{i, fmt.Sprint("product ", i), i % 3, (i * 37) % 1000}
+		}
+		close(orders)
+	}()
+	var result []Product
+	err := pipeline.In(orders).TopKBy(3, func(el interface{}, i int) interface{} {
+		return el.(Product).Price
+	}).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(result)).ToEqual(3)
+	e.Expect(result[0].Price).ToEqual(999)
+	e.Expect(result[2].Price).ToEqual(997)
+
+	err = pipeline.In([]interface{}{1, "a", 2}).TopKBy(2, func(el interface{}, i int) interface{} {
+		return el
+	}).Out(&[]interface{}{})
+	e.Expect(err).Not().ToBeNil()
+}
+
+func ExamplePipeline_TopK() {
+	var result []int
+	err := pipeline.In([]int{12, 3, 45, 7, 31, 8}).TopK(3, func(a, b interface{}) bool {
+		return a.(int) < b.(int)
+	}).Out(&result)
+	fmt.Print(result, " ", err)
+	// Output: [45 31 12] <nil>
+}
@@ -0,0 +1,214 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"container/heap"
+	"fmt"
+	"reflect"
+)
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// TopoSort sorts the elements of a collection so that each element comes after its dependencies
+func (pipeline *Pipeline) TopoSort(idFn func(element interface{}, index int) interface{}, depsFn func(element interface{}, index int) interface{}) *Pipeline {
+	return pipeline.push("TopoSort", func() (interface{}, error) {
+		return TopoSort(pipeline.in, idFn, depsFn)
+	}, idFn, depsFn)
+}
+
+// ConnectedComponents groups the elements of a collection that are linked to each other
+func (pipeline *Pipeline) ConnectedComponents(idFn func(element interface{}, index int) interface{}, neighboursFn func(element interface{}, index int) interface{}) *Pipeline {
+	return pipeline.push("ConnectedComponents", func() (interface{}, error) {
+		return ConnectedComponents(pipeline.in, idFn, neighboursFn)
+	}, idFn, neighboursFn)
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// TopoSort returns the elements of array in dependency order. depsFn returns the ids
+// of the dependencies of an element as a collection or nil, ids that are not in array are ignored.
+// Independent elements keep their original order.
+// It returns a CycleError listing the ids of the cycle if the dependencies are circular.
+func TopoSort(array interface{}, idFn func(element interface{}, index int) interface{}, depsFn func(element interface{}, index int) interface{}) (interface{}, error) {
+	elements, ids, edges, err := graphOf(array, idFn, depsFn)
+	if err != nil {
+		return nil, err
+	}
+	// pending counts the dependencies left for each element
+	pending := make([]int, len(elements))
+	dependents := make([][]int, len(elements))
+	for i, deps := range edges {
+		pending[i] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+	ready := &boundedHeap{worse: func(a, b heapEntry) bool {
+		return a.index < b.index
+	}}
+	for i := range elements {
+		if pending[i] == 0 {
+			heap.Push(ready, heapEntry{index: i})
+		}
+	}
+	result := []interface{}{}
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(heapEntry).index
+		result = append(result, elements[i])
+		for _, dependent := range dependents[i] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				heap.Push(ready, heapEntry{index: dependent})
+			}
+		}
+	}
+	if len(result) == len(elements) {
+		return result, nil
+	}
+	// follow the unresolved dependencies until an element is seen twice
+	current := 0
+	for pending[current] == 0 {
+		current++
+	}
+	seen := map[int]int{}
+	chain := []int{}
+	for {
+		if position, ok := seen[current]; ok {
+			cycle := []interface{}{}
+			for _, index := range append(chain[position:], current) {
+				cycle = append(cycle, ids[index])
+			}
+			return nil, CycleError{cycle}
+		}
+		seen[current] = len(chain)
+		chain = append(chain, current)
+		for _, dep := range edges[current] {
+			if pending[dep] > 0 {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// ConnectedComponents groups the elements of array linked by neighboursFn, which returns
+// the ids of the neighbours of an element as a collection or nil. Links are undirected and
+// ids that are not in array are ignored. It returns a [][]interface{}, components are ordered
+// by their first element and keep the order of array.
+func ConnectedComponents(array interface{}, idFn func(element interface{}, index int) interface{}, neighboursFn func(element interface{}, index int) interface{}) (interface{}, error) {
+	elements, _, edges, err := graphOf(array, idFn, neighboursFn)
+	if err != nil {
+		return nil, err
+	}
+	parents := make([]int, len(elements))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	for i, neighbours := range edges {
+		for _, neighbour := range neighbours {
+			a, b := find(i), find(neighbour)
+			// the smallest index is the root so that components are ordered by their first element
+			if a < b {
+				parents[b] = a
+			} else {
+				parents[a] = b
+			}
+		}
+	}
+	result := [][]interface{}{}
+	components := map[int]int{}
+	for i, element := range elements {
+		root := find(i)
+		if _, ok := components[root]; !ok {
+			components[root] = len(result)
+			result = append(result, []interface{}{})
+		}
+		result[components[root]] = append(result[components[root]], element)
+	}
+	return result, nil
+}
+
+// graphOf returns the elements of array, their ids and the indexes of the elements
+// each element is linked to
+func graphOf(array interface{}, idFn func(element interface{}, index int) interface{}, linksFn func(element interface{}, index int) interface{}) ([]interface{}, []interface{}, [][]int, error) {
+	if !IsIterable(array) {
+		return nil, nil, nil, NotIterableError{array}
+	}
+	elements := NewIterable(array).ToArrayOfInterface()
+	ids := []interface{}{}
+	indexes := map[interface{}]int{}
+	for i, element := range elements {
+		id := idFn(element, i)
+		if !isHashable(id) {
+			return nil, nil, nil, UnhashableIDError{id}
+		}
+		if _, ok := indexes[id]; !ok {
+			indexes[id] = i
+		}
+		ids = append(ids, id)
+	}
+	edges := make([][]int, len(elements))
+	for i, element := range elements {
+		links := linksFn(element, i)
+		if links == nil {
+			continue
+		}
+		if !IsIterable(links) {
+			return nil, nil, nil, NotIterableError{links}
+		}
+		linked := map[int]bool{}
+		iterable := NewIterable(links)
+		for j := 0; j < iterable.Length(); j++ {
+			link := iterable.At(j)
+			if !isHashable(link) {
+				return nil, nil, nil, UnhashableIDError{link}
+			}
+			if index, ok := indexes[link]; ok && !linked[index] {
+				linked[index] = true
+				edges[i] = append(edges[i], index)
+			}
+		}
+	}
+	return elements, ids, edges, nil
+}
+
+// isHashable tells whether value can be used as a map key
+func isHashable(value interface{}) bool {
+	return value == nil || reflect.ValueOf(value).Comparable()
+}
+
+// UnhashableIDError is returned when an id or a link cannot be used as a map key,
+// such as a slice or a map
+type UnhashableIDError struct {
+	id interface{}
+}
+
+// Error returns a string
+func (unhashableIDError UnhashableIDError) Error() string {
+	return fmt.Sprintf("Id %#v cannot be used as a map key", unhashableIDError.id)
+}
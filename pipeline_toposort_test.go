@@ -0,0 +1,113 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+type Task struct {
+	Name string
+	Deps []string
+}
+
+func taskName(el interface{}, i int) interface{} {
+	return el.(Task).Name
+}
+
+func taskDeps(el interface{}, i int) interface{} {
+	return el.(Task).Deps
+}
+
+func TestTopoSort(t *testing.T) {
+	e := expect.New(t)
+	var result []Task
+	err := pipeline.In([]Task{
+		{"deploy", []string{"test", "build"}},
+		{"test", []string{"build"}},
+		{"lint", nil},
+		{"build", []string{"fetch", "lint"}},
+	}).TopoSort(taskName, taskDeps).Out(&result)
+	e.Expect(err).ToBeNil()
+	names := []string{}
+	for _, task := range result {
+		names = append(names, task.Name)
+	}
+	e.Expect(fmt.Sprint(names)).ToEqual("[lint build test deploy]")
+
+	_, err = pipeline.TopoSort([]Task{
+		{"a", []string{"b"}},
+		{"b", []string{"c"}},
+		{"c", []string{"a"}},
+		{"d", nil},
+	}, taskName, taskDeps)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("Cycle detected : a -> b -> c -> a")
+}
+
+func TestConnectedComponents(t *testing.T) {
+	e := expect.New(t)
+	var result [][]interface{}
+	err := pipeline.In([]Task{
+		{"a", []string{"c"}},
+		{"b", nil},
+		{"c", nil},
+		{"d", []string{"b", "unknown"}},
+		{"e", nil},
+	}).ConnectedComponents(taskName, taskDeps).Out(&result)
+	e.Expect(err).ToBeNil()
+	components := []string{}
+	for _, component := range result {
+		names := ""
+		for _, task := range component {
+			names += task.(Task).Name
+		}
+		components = append(components, names)
+	}
+	e.Expect(fmt.Sprint(components)).ToEqual("[ac bd e]")
+}
+
+func TestTopoSortUnhashableIDs(t *testing.T) {
+	e := expect.New(t)
+	_, err := pipeline.TopoSort([]Task{{"a", nil}}, func(element interface{}, index int) interface{} {
+		return []string{element.(Task).Name}
+	}, taskDeps)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual(`Id []string{"a"} cannot be used as a map key`)
+
+	_, err = pipeline.ConnectedComponents([]Task{{"a", nil}}, taskName, func(element interface{}, index int) interface{} {
+		return [][]string{{"b"}}
+	})
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual(`Id []string{"b"} cannot be used as a map key`)
+}
+
+func ExamplePipeline_TopoSort() {
+	var result []string
+	dependencies := map[string][]string{"app": {"lib", "config"}, "lib": {"config"}}
+	err := pipeline.In([]string{"app", "lib", "config"}).TopoSort(func(el interface{}, i int) interface{} {
+		return el
+	}, func(el interface{}, i int) interface{} {
+		return dependencies[el.(string)]
+	}).Out(&result)
+	fmt.Print(result, " ", err)
+	// Output: [config lib app] <nil>
+}
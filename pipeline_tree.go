@@ -0,0 +1,377 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+//
+//    pipeline program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    pipeline program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with pipeline program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Node is a node of a tree built by Nest
+type Node struct {
+	Value    interface{}
+	Children []*Node
+}
+
+// DeepElement is an element of a recursive structure flattened by FlattenDeep or Unnest,
+// Depth is 0 for the roots.
+type DeepElement struct {
+	Value interface{}
+	Depth int
+}
+
+// TraversalOrder is the order in which recursive structures are flattened
+type TraversalOrder int
+
+const (
+	// DepthFirst visits the children of a node before its siblings
+	DepthFirst TraversalOrder = iota
+	// BreadthFirst visits all the nodes of a depth before the nodes of the next depth
+	BreadthFirst
+)
+
+/*********************************/
+/*            PIPELINE           */
+/*********************************/
+
+// Nest builds a tree of *Node from a flat collection where each element references its parent
+func (pipeline *Pipeline) Nest(idFn func(element interface{}, index int) interface{}, parentFn func(element interface{}, index int) interface{}) *Pipeline {
+	return pipeline.push("Nest", func() (interface{}, error) {
+		return Nest(pipeline.in, idFn, parentFn)
+	}, idFn, parentFn)
+}
+
+// Unnest flattens a tree of *Node into a collection of DeepElement
+func (pipeline *Pipeline) Unnest(order TraversalOrder) *Pipeline {
+	return pipeline.push("Unnest", func() (interface{}, error) {
+		return Unnest(pipeline.in, order)
+	}, order)
+}
+
+// FlattenDeep flattens a recursive structure into a collection of DeepElement
+func (pipeline *Pipeline) FlattenDeep(childrenFn func(element interface{}) interface{}, order TraversalOrder) *Pipeline {
+	return pipeline.push("FlattenDeep", func() (interface{}, error) {
+		return FlattenDeep(pipeline.in, childrenFn, order)
+	}, childrenFn, order)
+}
+
+// Walk visits nested slices and maps recursively, the collection is left unchanged
+func (pipeline *Pipeline) Walk(visitor func(path []interface{}, value interface{}) error) *Pipeline {
+	return pipeline.push("Walk", func() (interface{}, error) {
+		return Walk(pipeline.in, visitor)
+	}, visitor)
+}
+
+/*********************************/
+/*           FUNCTIONS           */
+/*********************************/
+
+// Nest builds a tree from a flat array in which each element references the id of its parent.
+// Elements whose parent is nil or cannot be found are the roots of the tree.
+// Children keep the order of the array. It returns []*Node, and an UnhashableIDError
+// if an id or a parent cannot be used as a map key.
+func Nest(array interface{}, idFn func(element interface{}, index int) interface{}, parentFn func(element interface{}, index int) interface{}) (interface{}, error) {
+	if !IsIterable(array) {
+		return nil, NotIterableError{array}
+	}
+	iterable := NewIterable(array)
+	nodes := []*Node{}
+	ids := []interface{}{}
+	parents := []interface{}{}
+	byID := map[interface{}]*Node{}
+	for i := 0; i < iterable.Length(); i++ {
+		node := &Node{Value: iterable.At(i), Children: []*Node{}}
+		id, parentID := idFn(node.Value, i), parentFn(node.Value, i)
+		for _, key := range []interface{}{id, parentID} {
+			if !isHashable(key) {
+				return nil, UnhashableIDError{key}
+			}
+		}
+		if _, ok := byID[id]; !ok {
+			byID[id] = node
+		}
+		nodes = append(nodes, node)
+		ids = append(ids, id)
+		parents = append(parents, parentID)
+	}
+	roots := []*Node{}
+	for i, node := range nodes {
+		parent, ok := byID[parents[i]]
+		if !ok || parent == nil {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	// nodes that cannot be reached from a root are part of a cycle
+	reached := map[*Node]bool{}
+	var reach func(node *Node)
+	reach = func(node *Node) {
+		reached[node] = true
+		for _, child := range node.Children {
+			reach(child)
+		}
+	}
+	for _, root := range roots {
+		reach(root)
+	}
+	for i, node := range nodes {
+		if reached[node] {
+			continue
+		}
+		// follow the parents until a node is seen twice
+		seen := map[int]int{}
+		chain := []int{}
+		for current := i; ; current = indexOfNode(nodes, byID[parents[current]]) {
+			if position, ok := seen[current]; ok {
+				cycle := []interface{}{}
+				for _, index := range append(chain[position:], current) {
+					cycle = append(cycle, ids[index])
+				}
+				return nil, CycleError{cycle}
+			}
+			seen[current] = len(chain)
+			chain = append(chain, current)
+		}
+	}
+	return roots, nil
+}
+
+// Unnest flattens a tree of *Node built by Nest, returning the values of the nodes
+// as DeepElement. It returns a NotANodeError if an element of the tree is not a *Node.
+func Unnest(tree interface{}, order TraversalOrder) (interface{}, error) {
+	if node, ok := tree.(*Node); ok {
+		tree = []*Node{node}
+	}
+	var notANode error
+	result, err := FlattenDeep(tree, func(element interface{}) interface{} {
+		node, ok := element.(*Node)
+		if !ok || node == nil {
+			if notANode == nil {
+				notANode = NotANodeError{element}
+			}
+			return nil
+		}
+		return node.Children
+	}, order)
+	if err != nil {
+		return nil, err
+	}
+	if notANode != nil {
+		return nil, notANode
+	}
+	elements := result.([]interface{})
+	for i := range elements {
+		deepElement := elements[i].(DeepElement)
+		deepElement.Value = deepElement.Value.(*Node).Value
+		elements[i] = deepElement
+	}
+	return elements, nil
+}
+
+// FlattenDeep flattens a recursive structure. Each element of array is a root,
+// childrenFn returns the children of an element as a collection or nil.
+// It returns a []interface{} of DeepElement, and a CycleError if an element is its own ancestor.
+func FlattenDeep(array interface{}, childrenFn func(element interface{}) interface{}, order TraversalOrder) (interface{}, error) {
+	if !IsIterable(array) {
+		return nil, NotIterableError{array}
+	}
+	type item struct {
+		value  interface{}
+		depth  int
+		parent *item
+	}
+	children := func(parent *item) ([]*item, error) {
+		collection := childrenFn(parent.value)
+		if collection == nil || (reflect.ValueOf(collection).Kind() == reflect.Slice && reflect.ValueOf(collection).IsNil()) {
+			return nil, nil
+		}
+		if !IsIterable(collection) {
+			return nil, NotIterableError{collection}
+		}
+		iterable := NewIterable(collection)
+		items := []*item{}
+		for i := 0; i < iterable.Length(); i++ {
+			child := &item{iterable.At(i), parent.depth + 1, parent}
+			if key, ok := identity(child.value); ok {
+				for ancestor := parent; ancestor != nil; ancestor = ancestor.parent {
+					if ancestorKey, ok := identity(ancestor.value); ok && ancestorKey == key {
+						cycle := []interface{}{child.value}
+						for current := parent; current != ancestor.parent; current = current.parent {
+							cycle = append([]interface{}{current.value}, cycle...)
+						}
+						return nil, CycleError{cycle}
+					}
+				}
+			}
+			items = append(items, child)
+		}
+		return items, nil
+	}
+	iterable := NewIterable(array)
+	pending := []*item{}
+	for i := 0; i < iterable.Length(); i++ {
+		pending = append(pending, &item{value: iterable.At(i)})
+	}
+	result := []interface{}{}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		result = append(result, DeepElement{current.value, current.depth})
+		items, err := children(current)
+		if err != nil {
+			return nil, err
+		}
+		if order == BreadthFirst {
+			pending = append(pending, items...)
+		} else {
+			pending = append(items, pending...)
+		}
+	}
+	return result, nil
+}
+
+// Walk visits value and the elements of nested slices, arrays and maps recursively,
+// calling visitor with the path of indexes and keys leading to each element.
+// Map keys are visited in order. Walk stops at the first error returned by visitor and
+// returns a CycleError if a slice, map or pointer contains itself. It returns value unchanged.
+func Walk(value interface{}, visitor func(path []interface{}, value interface{}) error) (interface{}, error) {
+	type visit struct {
+		key    identityKey
+		parent *visit
+	}
+	var walk func(path []interface{}, value interface{}, ancestors *visit) error
+	walk = func(path []interface{}, value interface{}, ancestors *visit) error {
+		if key, ok := identity(value); ok {
+			for ancestor := ancestors; ancestor != nil; ancestor = ancestor.parent {
+				if ancestor.key == key {
+					return CycleError{append([]interface{}{}, path...)}
+				}
+			}
+			ancestors = &visit{key, ancestors}
+		}
+		if err := visitor(path, value); err != nil {
+			return err
+		}
+		v := reflect.ValueOf(value)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				if err := walk(append(path[:len(path):len(path)], i), v.Index(i).Interface(), ancestors); err != nil {
+					return err
+				}
+			}
+		case reflect.Map:
+			for _, key := range sortedKeys(v) {
+				if err := walk(append(path[:len(path):len(path)], key.Interface()), v.MapIndex(key).Interface(), ancestors); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk([]interface{}{}, value, nil); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+/*********************************/
+/*            ERRORS             */
+/*********************************/
+
+// CycleError discriminates a structure that references itself
+type CycleError struct {
+	cycle []interface{}
+}
+
+// Error returns a string
+func (cycleError CycleError) Error() string {
+	steps := []string{}
+	for _, step := range cycleError.cycle {
+		steps = append(steps, fmt.Sprint(step))
+	}
+	return fmt.Sprintf("Cycle detected : %s", strings.Join(steps, " -> "))
+}
+
+// NotANodeError discriminates an element of a tree that is not a *Node
+type NotANodeError struct {
+	value interface{}
+}
+
+// Error returns a string
+func (notANodeError NotANodeError) Error() string {
+	return fmt.Sprintf("%#v is not a *Node", notANodeError.value)
+}
+
+/*********************************/
+/*             HELPERS           */
+/*********************************/
+
+// sortedKeys returns the keys of a map, numbers and strings in order, other keys
+// in the order of their formatting
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		if order, err := compare(keys[i].Interface(), keys[j].Interface()); err == nil {
+			return order < 0
+		}
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+type identityKey struct {
+	kind    reflect.Kind
+	pointer uintptr
+	length  int
+}
+
+// identity returns a key identifying values that can reference themselves
+func identity(value interface{}) (identityKey, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if v.IsNil() {
+			return identityKey{}, false
+		}
+		return identityKey{v.Kind(), v.Pointer(), 0}, true
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return identityKey{}, false
+		}
+		return identityKey{v.Kind(), v.Pointer(), v.Len()}, true
+	}
+	return identityKey{}, false
+}
+
+func indexOfNode(nodes []*Node, node *Node) int {
+	for i, candidate := range nodes {
+		if candidate == node {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,135 @@
+//    pipeline is a functional programming library for go
+//    Copyright (C) 2015 mparaiso <mparaiso@online.fr>
+
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pipeline_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interactiv/expect"
+	"github.com/interactiv/pipeline"
+)
+
+type Category struct {
+	ID       int
+	ParentID int
+	Name     string
+}
+
+var categories = []Category{{1, 0, "Electronics"}, {2, 1, "Phones"}, {3, 0, "Books"}, {4, 1, "Laptops"}, {5, 2, "Smartphones"}}
+
+func categoryID(el interface{}, i int) interface{} {
+	return el.(Category).ID
+}
+
+func categoryParentID(el interface{}, i int) interface{} {
+	return el.(Category).ParentID
+}
+
+func TestNest(t *testing.T) {
+	e := expect.New(t)
+	var roots []*pipeline.Node
+	err := pipeline.In(categories).Nest(categoryID, categoryParentID).Out(&roots)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(roots)).ToEqual(2)
+	e.Expect(roots[0].Value.(Category).Name).ToEqual("Electronics")
+	e.Expect(len(roots[0].Children)).ToEqual(2)
+	e.Expect(roots[0].Children[0].Children[0].Value.(Category).Name).ToEqual("Smartphones")
+
+	_, err = pipeline.Nest([]Category{{1, 0, "root"}, {2, 3, "a"}, {3, 4, "b"}, {4, 2, "c"}}, categoryID, categoryParentID)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(strings.Contains(err.Error(), "2 -> 3 -> 4 -> 2")).ToBeTrue()
+
+	_, err = pipeline.Nest(categories, categoryID, func(element interface{}, index int) interface{} {
+		return map[string]int{"id": element.(Category).ParentID}
+	})
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(strings.HasPrefix(err.Error(), "Id map[string]int{")).ToBeTrue()
+}
+
+func TestUnnest(t *testing.T) {
+	e := expect.New(t)
+	var result []pipeline.DeepElement
+	err := pipeline.In(categories).Nest(categoryID, categoryParentID).Unnest(pipeline.DepthFirst).Out(&result)
+	e.Expect(err).ToBeNil()
+	names := []string{}
+	for _, element := range result {
+		names = append(names, fmt.Sprint(element.Value.(Category).Name, ":", element.Depth))
+	}
+	e.Expect(strings.Join(names, " ")).ToEqual("Electronics:0 Phones:1 Smartphones:2 Laptops:1 Books:0")
+
+	err = pipeline.In(categories).Nest(categoryID, categoryParentID).Unnest(pipeline.BreadthFirst).Out(&result)
+	e.Expect(err).ToBeNil()
+	names = []string{}
+	for _, element := range result {
+		names = append(names, element.Value.(Category).Name)
+	}
+	e.Expect(strings.Join(names, " ")).ToEqual("Electronics Books Phones Laptops Smartphones")
+
+	_, err = pipeline.Unnest([]interface{}{"root"}, pipeline.DepthFirst)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual(`"root" is not a *Node`)
+	_, err = pipeline.Unnest(&pipeline.Node{Value: 1, Children: []*pipeline.Node{nil}}, pipeline.DepthFirst)
+	e.Expect(err).Not().ToBeNil()
+	e.Expect(err.Error()).ToEqual("(*pipeline.Node)(nil) is not a *Node")
+}
+
+type Folder struct {
+	Name    string
+	Folders []*Folder
+}
+
+func TestFlattenDeep(t *testing.T) {
+	e := expect.New(t)
+	root := &Folder{"/", []*Folder{{"home", []*Folder{{"john", nil}}}, {"tmp", nil}}}
+	children := func(el interface{}) interface{} {
+		return el.(*Folder).Folders
+	}
+	var result []pipeline.DeepElement
+	err := pipeline.In([]*Folder{root}).FlattenDeep(children, pipeline.DepthFirst).Out(&result)
+	e.Expect(err).ToBeNil()
+	e.Expect(len(result)).ToEqual(4)
+	e.Expect(result[2].Value.(*Folder).Name).ToEqual("john")
+	e.Expect(result[2].Depth).ToEqual(2)
+
+	root.Folders[0].Folders[0].Folders = []*Folder{root}
+	err = pipeline.In([]*Folder{root}).FlattenDeep(children, pipeline.BreadthFirst).Out(&result)
+	e.Expect(err).Not().ToBeNil()
+}
+
+func TestWalk(t *testing.T) {
+	e := expect.New(t)
+	document := map[string]interface{}{
+		"name": "pipeline",
+		"tags": []string{"go", "functional"},
+	}
+	paths := []string{}
+	err := pipeline.In(document).Walk(func(path []interface{}, value interface{}) error {
+		paths = append(paths, fmt.Sprint(path))
+		return nil
+	}).Out(&map[string]interface{}{})
+	e.Expect(err).ToBeNil()
+	e.Expect(strings.Join(paths, " ")).ToEqual("[] [name] [tags] [tags 0] [tags 1]")
+
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+	_, err = pipeline.Walk(cyclic, func(path []interface{}, value interface{}) error {
+		return nil
+	})
+	e.Expect(err).Not().ToBeNil()
+}